@@ -0,0 +1,142 @@
+// Package client is the bible as a Go library, so other programs can look things up without running the bible program.
+//
+//	b, err := client.Open("kjv.db")
+//	if err != nil {
+//		...
+//	}
+//	defer b.Close()
+//
+//	verses, err := b.Passage("John 3:16-18")
+//
+// Nothing in here prints anything, everything gives back an error instead. If something doesn't exist
+// (a book, chapter or verse), the error is client.ErrNotFound, so check for it with errors.Is.
+package client
+
+import (
+	"database/sql"
+	f "bible/functions"
+)
+
+
+// A verse. The json names are id, bookName, book, chapter, verse and text
+type Verse = f.Bible
+
+// A book with how many chapters it has
+type Book = f.BookInfo
+
+// A chapter with how many verses it has
+type Chapter = f.ChapterInfo
+
+// Options for searching. See functions.ParseSearch for what a search can have in it
+type SearchOptions = f.SearchOptions
+
+// How many search matches there were in a book
+type BookCount = f.BookCount
+
+
+// What the lookups give back when a book, chapter or verse doesn't exist
+var ErrNotFound = f.ErrNotFound
+
+
+// Bible is one translation of the bible. It's safe to use from more than one goroutine, same as *sql.DB
+type Bible struct {
+	db		*sql.DB
+	owned	bool	// Close only closes the database if it was opened here
+}
+
+
+// New uses a database that is already open. Closing the Bible won't close it, use functions.CloseDatabase for that.
+func New(db *sql.DB) *Bible {
+	return &Bible{db: db}
+}
+
+
+// Open opens a bible database file (like kjv.db, or one made by tool/json_to_sqlite.go)
+func Open(path string) (*Bible, error) {
+	db, err := f.OpenDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Bible{db: db, owned: true}, nil
+}
+
+
+// OpenTranslation opens a translation by name, from the ones registered or installed (see functions.Translations)
+func OpenTranslation(name string) (*Bible, error) {
+	db, err := f.OpenTranslation(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Bible{db: db, owned: true}, nil
+}
+
+
+// Close closes the database, if it was opened by Open or OpenTranslation
+func (b *Bible) Close() error {
+	if !b.owned {
+		return nil
+	}
+	return f.CloseDatabase(b.db)
+}
+
+
+// DB gives back the database, for anything that isn't in here
+func (b *Bible) DB() *sql.DB {
+	return b.db
+}
+
+
+// Passage gives back every verse in a reference, ie "John 3:16-18", "Genesis 1", "1 Cor 13:4-7; Jude 5" or "Malachi 4 - Matthew 1"
+func (b *Bible) Passage(ref string) ([]Verse, error) {
+	return f.LookupPassage(b.db, ref)
+}
+
+
+// Verse gives back one verse. The book can be an abbreviation, ie "Jn"
+func (b *Bible) Verse(book string, chapter int, verse int) (Verse, error) {
+	name, err := f.ResolveBook(book)
+	if err != nil {
+		return Verse{}, err
+	}
+	return f.LookupVerse(b.db, name, chapter, verse)
+}
+
+
+// VerseByID gives back a verse by its id. The ids go in order through the whole bible, starting at 1 (Genesis 1:1)
+func (b *Bible) VerseByID(id int) (Verse, error) {
+	return f.LookupVerseId(b.db, id)
+}
+
+
+// Random gives back a random verse
+func (b *Bible) Random() (Verse, error) {
+	return f.LookupRandomVerse(b.db)
+}
+
+
+// Search gives back the verses that match a search, ie "love", "faith NEAR/5 hope" or "bless* OR grace"
+func (b *Bible) Search(q string, opts SearchOptions) ([]Verse, error) {
+	return f.Search(b.db, q, opts)
+}
+
+
+// SearchCounts gives back how many verses match a search in each book, in bible order. Limit and Offset don't change it
+func (b *Bible) SearchCounts(q string, opts SearchOptions) ([]BookCount, error) {
+	return f.CountSearchResults(b.db, q, opts)
+}
+
+
+// Books gives back every book, in order, with how many chapters each one has
+func (b *Bible) Books() ([]Book, error) {
+	return f.ListBooks(b.db)
+}
+
+
+// Chapters gives back every chapter in a book, with how many verses each one has. The book can be an abbreviation
+func (b *Bible) Chapters(book string) ([]Chapter, error) {
+	name, err := f.ResolveBook(book)
+	if err != nil {
+		return nil, err
+	}
+	return f.ListChapters(b.db, name)
+}
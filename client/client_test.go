@@ -0,0 +1,144 @@
+package client
+
+import (
+	"os"
+	"errors"
+	"testing"
+	"path/filepath"
+	"database/sql"
+	f "bible/functions"
+)
+
+
+// Makes a tiny bible (John 3:16-17 and Jude 1:1) and gives back the path to it
+func writeTestBible(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open(f.DriverName, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, query := range []string{
+		"CREATE TABLE bible (id INTEGER PRIMARY KEY, bookName TEXT, book INTEGER, chapter INTEGER, verse INTEGER, text TEXT)",
+		"INSERT INTO bible VALUES (1, 'John', 43, 3, 16, 'For God so loved the world')",
+		"INSERT INTO bible VALUES (2, 'John', 43, 3, 17, 'For God sent not his Son into the world to condemn the world')",
+		"INSERT INTO bible VALUES (3, 'Jude', 65, 1, 1, 'Jude, the servant of Jesus Christ')",
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+
+func openTestBible(t *testing.T) *Bible {
+	t.Helper()
+	b, err := Open(writeTestBible(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+
+func TestPassage(t *testing.T) {
+	b := openTestBible(t)
+
+	verses, err := b.Passage("Jn 3:16-17; Jude 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verses) != 3 || verses[0].Text != "For God so loved the world" || verses[2].BookName != "Jude" {
+		t.Errorf("Passage(\"Jn 3:16-17; Jude 1\") = %+v", verses)
+	}
+
+	if _, err := b.Passage("John 3:16-"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("A reference that doesn't make sense should give an error that isn't ErrNotFound, got %v", err)
+	}
+	if _, err := b.Passage("Romans 8:28"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Passage(\"Romans 8:28\") = %v, want ErrNotFound", err)
+	}
+}
+
+
+func TestVerse(t *testing.T) {
+	b := openTestBible(t)
+
+	verse, err := b.Verse("jn", 3, 17)
+	if err != nil || verse.ID != 2 || verse.Book != 43 {
+		t.Errorf("Verse(\"jn\", 3, 17) = %+v, %v", verse, err)
+	}
+	if verse, err := b.VerseByID(3); err != nil || verse.BookName != "Jude" {
+		t.Errorf("VerseByID(3) = %+v, %v", verse, err)
+	}
+
+	for _, err := range []error{
+		func() error { _, err := b.Verse("John", 3, 18); return err }(),
+		func() error { _, err := b.Verse("Jhon", 3, 16); return err }(),
+		func() error { _, err := b.VerseByID(4); return err }(),
+		func() error { _, err := b.Chapters("Romans"); return err }(),
+	} {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Got %v, want ErrNotFound", err)
+		}
+	}
+}
+
+
+func TestBooksAndChapters(t *testing.T) {
+	b := openTestBible(t)
+
+	books, err := b.Books()
+	if err != nil || len(books) != 2 || books[0] != (Book{Name: "John", Book: 43, Chapters: 1}) {
+		t.Errorf("Books() = %+v, %v", books, err)
+	}
+
+	chapters, err := b.Chapters("John")
+	if err != nil || len(chapters) != 1 || chapters[0] != (Chapter{Chapter: 3, Verses: 2}) {
+		t.Errorf("Chapters(\"John\") = %+v, %v", chapters, err)
+	}
+}
+
+
+func TestSearch(t *testing.T) {
+	b := openTestBible(t)
+
+	verses, err := b.Search("world", SearchOptions{Exact: true})
+	if err != nil || len(verses) != 2 {
+		t.Errorf("Search(\"world\") = %+v, %v", verses, err)
+	}
+
+	counts, err := b.SearchCounts("God OR Jesus", SearchOptions{})
+	if err != nil || len(counts) != 2 || counts[0] != (BookCount{BookName: "John", Count: 2}) {
+		t.Errorf("SearchCounts(\"God OR Jesus\") = %+v, %v", counts, err)
+	}
+}
+
+
+func TestOpenNotABible(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.db")
+	os.WriteFile(path, []byte("not a bible"), 0644)
+	if b, err := Open(path); err == nil {
+		b.Close()
+		t.Errorf("Open(%q) should give an error", path)
+	}
+}
+
+
+func TestNewDoesNotClose(t *testing.T) {
+	db, err := sql.Open(f.DriverName, writeTestBible(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	b := New(db)
+	b.Close()
+	if err := db.Ping(); err != nil {
+		t.Errorf("Closing a Bible from New closed the database: %v", err)
+	}
+}
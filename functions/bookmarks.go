@@ -0,0 +1,172 @@
+package functions
+
+import (
+	"fmt"
+	"time"
+	"strings"
+	"database/sql"
+)
+
+
+// A bookmark with a name, so more than one person (or reading) can have their own place.
+// The one called "default" is the same as SaveData.Bookmark, which is what the bookmark was before it could have a name
+type NamedBookmark struct {
+	Name	string		`json:"name"`
+	ID		int			`json:"id"`
+	Updated	time.Time	`json:"updated"`
+}
+
+
+// The name of the bookmark you get if you don't give one
+const defaultBookmark = "default"
+
+
+// GetBookmark gives back the verse id of a bookmark, and false if there isn't one with that name
+func (sd *SaveData) GetBookmark(name string) (int, bool) {
+	name = bookmarkName(name)
+	for _, b := range sd.Bookmarks {
+		if b.Name == name {
+			return b.ID, true
+		}
+	}
+
+	// The default one might only be in the old place if it was saved before bookmarks had names
+	if name == defaultBookmark && sd.Bookmark != 0 {
+		return sd.Bookmark, true
+	}
+	return 0, false
+}
+
+
+// SetBookmark saves a bookmark, or moves it if there is already one with that name
+func (ut *UserTx) SetBookmark(name string, id int) error {
+	_, err := ut.tx.Exec(`INSERT INTO bookmarks (name, verse_id, updated) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET verse_id = excluded.verse_id, updated = excluded.updated`,
+		bookmarkName(name), id, formatTime(time.Now()))
+	return err
+}
+
+
+// RemoveBookmark removes a bookmark. It gives back false if there wasn't one with that name
+func (ut *UserTx) RemoveBookmark(name string) (bool, error) {
+	return ut.execChanged("DELETE FROM bookmarks WHERE name = ?", bookmarkName(name))
+}
+
+
+// AllBookmarks gives back every bookmark, including the default one from before bookmarks had names
+func (sd *SaveData) AllBookmarks() []NamedBookmark {
+	all := append([]NamedBookmark{}, sd.Bookmarks...)
+	if _, named := sd.namedDefault(); !named && sd.Bookmark != 0 {
+		all = append([]NamedBookmark{{Name: defaultBookmark, ID: sd.Bookmark}}, all...)
+	}
+	return all
+}
+
+
+func (sd *SaveData) namedDefault() (NamedBookmark, bool) {
+	for _, b := range sd.Bookmarks {
+		if b.Name == defaultBookmark {
+			return b, true
+		}
+	}
+	return NamedBookmark{}, false
+}
+
+
+// Names are lower case with no spaces on the ends, and no name is the default one
+func bookmarkName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return defaultBookmark
+	}
+	return name
+}
+
+
+// This is the bookmark function in interactive mode. It loads or saves a bookmark, and gives back the id of the verse to go to
+func BookMark(db *sql.DB, id int) int {
+	var choice string
+	for choice != "l" && choice != "s" {
+		choice = askLine("Would you like to load or save bookmark? (l or s) ")
+		// Nothing at all (or nothing left to read) just goes back to the verse
+		if choice == "" {
+			return id
+		}
+		if choice != "l" && choice != "s" {
+			fmt.Println("Please select either l or s")
+		}
+	}
+
+	// Load the bookmark
+	if choice == "l" {
+		return LoadBookmark(db, id)
+	}
+
+	name, ok := askLineOk("Bookmark name? (enter for default) ")
+	if !ok {
+		return id
+	}
+
+	// Save it
+	err := UpdateUserData(func(ut *UserTx) error {
+		return ut.SetBookmark(name, id)
+	})
+	if err != nil {
+		fmt.Println("Error saving data:", err)
+		return id
+	}
+
+	fmt.Printf("Saved Bookmark %s!\n", bookmarkName(name))
+
+	// Just keep the user on the same verse.
+	return id
+}
+
+
+// This is the load function for interactive mode (at the beginning, and with 'b' then 'l'). If there is more than one
+// bookmark it asks which one. It gives back the id of the bookmark, or current if there isn't one to load.
+func LoadBookmark(db *sql.DB, current int) int {
+	saveData, err := LoadSaveData()
+	if err != nil {
+		fmt.Println("Error loading data:", err)
+		return max(current, 1)
+	}
+
+	bookmarks := saveData.AllBookmarks()
+
+	// If a bookmark hasn't been saved yet, just send the user to Genesis 1 1 (or keep them where they are)
+	if len(bookmarks) == 0 {
+		fmt.Println("No bookmarks saved yet")
+		return max(current, 1)
+	}
+
+	name := defaultBookmark
+	if len(bookmarks) > 1 {
+		PrintBookmarks(db, bookmarks)
+		var ok bool
+		if name, ok = askLineOk("Which bookmark? (enter for default) "); !ok {
+			return max(current, 1)
+		}
+	} else {
+		name = bookmarks[0].Name
+	}
+
+	id, ok := saveData.GetBookmark(name)
+	if !ok {
+		fmt.Printf("There isn't a bookmark called %s\n", bookmarkName(name))
+		return max(current, 1)
+	}
+	return id
+}
+
+
+// PrintBookmarks prints the bookmarks with the verse they are on and when they were saved
+func PrintBookmarks(db *sql.DB, bookmarks []NamedBookmark) {
+	for _, b := range bookmarks {
+		updated := "-"
+		if !b.Updated.IsZero() {
+			updated = b.Updated.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-16s %-24s %s\n", b.Name, IdRangeString(db, b.ID, b.ID), updated)
+	}
+}
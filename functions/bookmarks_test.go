@@ -0,0 +1,164 @@
+package functions
+
+import (
+	"testing"
+)
+
+
+func TestNamedBookmarks(t *testing.T) {
+	// Saved before bookmarks had names
+	data := SaveData{Bookmark: 10}
+	if id, ok := data.GetBookmark(""); !ok || id != 10 {
+		t.Errorf("GetBookmark(\"\") = %d, %v, want the old bookmark 10", id, ok)
+	}
+	if all := data.AllBookmarks(); len(all) != 1 || all[0].Name != defaultBookmark || all[0].ID != 10 {
+		t.Errorf("AllBookmarks() = %+v, want only the old default one", all)
+	}
+
+	useTempUserDb(t)
+	err := UpdateUserData(func(ut *UserTx) error {
+		ut.SetBookmark(" Evening ", 140)
+		ut.SetBookmark("", 20)
+		return ut.SetBookmark("EVENING", 150)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, ok := saved.GetBookmark("evening"); !ok || id != 150 {
+		t.Errorf("GetBookmark(\"evening\") = %d, %v, want 150", id, ok)
+	}
+	// The default one is still in the old place too, for older versions
+	if saved.Bookmark != 20 {
+		t.Errorf("Bookmark = %d, want 20", saved.Bookmark)
+	}
+	all := saved.AllBookmarks()
+	if len(all) != 2 || all[0].Name != defaultBookmark || all[0].ID != 20 || all[1].Name != "evening" {
+		t.Errorf("AllBookmarks() = %+v, want default and evening", all)
+	}
+
+	var removed, removedMissing bool
+	err = UpdateUserData(func(ut *UserTx) error {
+		var err error
+		if removed, err = ut.RemoveBookmark("default"); err != nil {
+			return err
+		}
+		removedMissing, err = ut.RemoveBookmark("morning")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !removed || removedMissing {
+		t.Errorf("RemoveBookmark() gave back %v for default and %v for morning, want true and false", removed, removedMissing)
+	}
+	if saved, err = LoadSaveData(); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Bookmark != 0 {
+		t.Errorf("RemoveBookmark(\"default\") didn't remove it: %+v", saved)
+	}
+	if _, ok := saved.GetBookmark(""); ok {
+		t.Errorf("GetBookmark(\"\") found the removed default bookmark")
+	}
+}
+
+
+func TestSaveBookmark(t *testing.T) {
+	db := newTestBibleDb(t)
+
+	tests := []struct {
+		input	string
+		want	map[string]int
+	}{
+		{"s\nEvening\n", map[string]int{"evening": 57}},
+		{"s\n\n", map[string]int{defaultBookmark: 57}},
+		{"x\ns\nmorning\n", map[string]int{"morning": 57}},
+		// Nothing left to read cancels it, instead of saving a bookmark called "q"
+		{"s\n", map[string]int{}},
+		{"", map[string]int{}},
+	}
+
+	for _, test := range tests {
+		useTempUserDb(t)
+		useTestInput(t, test.input)
+
+		var id int
+		captureOutput(t, func() { id = BookMark(db, 57) })
+		if id != 57 {
+			t.Errorf("BookMark() with %q went to %d, want to stay on 57", test.input, id)
+		}
+
+		saveData, err := LoadSaveData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]int{}
+		for _, b := range saveData.AllBookmarks() {
+			got[b.Name] = b.ID
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("BookMark() with %q saved %v, want %v", test.input, got, test.want)
+			continue
+		}
+		for name, id := range test.want {
+			if got[name] != id {
+				t.Errorf("BookMark() with %q saved %v, want %v", test.input, got, test.want)
+			}
+		}
+	}
+}
+
+
+func TestLoadBookmark(t *testing.T) {
+	useTempUserDb(t)
+	db := newTestBibleDb(t)
+
+	// Nothing saved yet
+	var got int
+	captureOutput(t, func() { got = LoadBookmark(db, 0) })
+	if got != 1 {
+		t.Errorf("LoadBookmark() with no bookmarks = %d, want Genesis 1:1", got)
+	}
+
+	err := UpdateUserData(func(ut *UserTx) error {
+		return ut.SetBookmark("", 10)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only one, so it doesn't ask
+	captureOutput(t, func() { got = LoadBookmark(db, 57) })
+	if got != 10 {
+		t.Errorf("LoadBookmark() with one bookmark = %d, want 10", got)
+	}
+
+	err = UpdateUserData(func(ut *UserTx) error {
+		return ut.SetBookmark("evening", 140)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input	string
+		want	int
+	}{
+		{"evening\n", 140},
+		{"\n", 10},
+		// Not one of them, or nothing left to read, stays where it was
+		{"morning\n", 57},
+		{"", 57},
+	}
+
+	for _, test := range tests {
+		useTestInput(t, test.input)
+		captureOutput(t, func() { got = LoadBookmark(db, 57) })
+		if got != test.want {
+			t.Errorf("LoadBookmark() with %q = %d, want %d", test.input, got, test.want)
+		}
+	}
+}
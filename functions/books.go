@@ -0,0 +1,304 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+)
+
+
+// All the other names people use for the books. These are mostly the SBL abbreviations and the OSIS ids,
+// plus a few of the common ones. The full name of the book always works, so it doesn't need to be in here.
+// Case, spaces and dots don't matter, so "1 Cor.", "1cor" and "1 COR" are all the same thing.
+var bookAliases = map[string][]string{
+	"Genesis":         {"Gen", "Ge", "Gn"},
+	"Exodus":          {"Exod", "Exo", "Ex"},
+	"Leviticus":       {"Lev", "Le", "Lv"},
+	"Numbers":         {"Num", "Nu", "Nm", "Nb"},
+	"Deuteronomy":     {"Deut", "Dt", "De"},
+	"Joshua":          {"Josh", "Jos", "Jsh"},
+	"Judges":          {"Judg", "Jdg", "Jg", "Jdgs"},
+	"Ruth":            {"Rth", "Ru"},
+	"1 Samuel":        {"1 Sam", "1 Sa", "1 Sm", "1 S"},
+	"2 Samuel":        {"2 Sam", "2 Sa", "2 Sm", "2 S"},
+	"1 Kings":         {"1 Kgs", "1 Ki", "1 Kin"},
+	"2 Kings":         {"2 Kgs", "2 Ki", "2 Kin"},
+	"1 Chronicles":    {"1 Chr", "1 Ch", "1 Chron"},
+	"2 Chronicles":    {"2 Chr", "2 Ch", "2 Chron"},
+	"Ezra":            {"Ezr"},
+	"Nehemiah":        {"Neh", "Ne"},
+	"Esther":          {"Esth", "Est", "Es"},
+	"Job":             {"Jb"},
+	"Psalms":          {"Ps", "Psa", "Pss", "Psm", "Pslm"},
+	"Proverbs":        {"Prov", "Pro", "Prv", "Pr"},
+	"Ecclesiastes":    {"Eccl", "Eccles", "Ecc", "Ec", "Qoh", "Qoheleth"},
+	"Song of Solomon": {"Song", "Song of Songs", "SOS", "So", "Sg", "Cant", "Canticles"},
+	"Isaiah":          {"Isa", "Is"},
+	"Jeremiah":        {"Jer", "Je", "Jr"},
+	"Lamentations":    {"Lam", "La"},
+	"Ezekiel":         {"Ezek", "Eze", "Ezk"},
+	"Daniel":          {"Dan", "Da", "Dn"},
+	"Hosea":           {"Hos", "Ho"},
+	"Joel":            {"Jl"},
+	"Amos":            {"Am"},
+	"Obadiah":         {"Obad", "Ob"},
+	"Jonah":           {"Jon", "Jnh"},
+	"Micah":           {"Mic", "Mc"},
+	"Nahum":           {"Nah", "Na"},
+	"Habakkuk":        {"Hab", "Hb"},
+	"Zephaniah":       {"Zeph", "Zep", "Zp"},
+	"Haggai":          {"Hag", "Hg"},
+	"Zechariah":       {"Zech", "Zec", "Zc"},
+	"Malachi":         {"Mal", "Ml"},
+	"Matthew":         {"Matt", "Mt"},
+	"Mark":            {"Mk", "Mrk", "Mar"},
+	"Luke":            {"Lk", "Luk"},
+	"John":            {"Jn", "Jhn", "Joh"},
+	"Acts":            {"Ac", "Acts of the Apostles"},
+	"Romans":          {"Rom", "Ro", "Rm"},
+	"1 Corinthians":   {"1 Cor", "1 Co"},
+	"2 Corinthians":   {"2 Cor", "2 Co"},
+	"Galatians":       {"Gal", "Ga"},
+	"Ephesians":       {"Eph", "Ephes"},
+	"Philippians":     {"Phil", "Php", "Pp"},
+	"Colossians":      {"Col"},
+	"1 Thessalonians": {"1 Thess", "1 Thes", "1 Th"},
+	"2 Thessalonians": {"2 Thess", "2 Thes", "2 Th"},
+	"1 Timothy":       {"1 Tim", "1 Ti"},
+	"2 Timothy":       {"2 Tim", "2 Ti"},
+	"Titus":           {"Tit"},
+	"Philemon":        {"Phlm", "Philem", "Phm"},
+	"Hebrews":         {"Heb"},
+	"James":           {"Jas", "Jm"},
+	"1 Peter":         {"1 Pet", "1 Pe", "1 Pt"},
+	"2 Peter":         {"2 Pet", "2 Pe", "2 Pt"},
+	"1 John":          {"1 Jn", "1 Jhn", "1 Jo"},
+	"2 John":          {"2 Jn", "2 Jhn", "2 Jo"},
+	"3 John":          {"3 Jn", "3 Jhn", "3 Jo"},
+	"Jude":            {"Jud", "Jd"},
+	"Revelation":      {"Rev", "Re", "Rv", "Apocalypse", "Revelation of John"},
+}
+
+
+// This is every name (full names and aliases) squished down with normalizeBookName, pointing at the real name of the book
+var bookLookup = buildBookLookup()
+
+
+func buildBookLookup() map[string]string {
+	lookup := make(map[string]string)
+	for _, book := range allBooks {
+		lookup[normalizeBookName(book)] = book
+		for _, alias := range bookAliases[book] {
+			lookup[normalizeBookName(alias)] = book
+		}
+	}
+	return lookup
+}
+
+
+// Squishes a book name down so it's easy to compare. Lowercase, no dots or spaces, and roman numerals
+// or words for numbered books turned into digits. So "I Cor.", "First Corinthians" and "1cor" all start with "1co"
+func normalizeBookName(name string) string {
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(name, ".", " ")))
+	if len(fields) > 1 {
+		switch fields[0] {
+		case "i", "first", "1st":
+			fields[0] = "1"
+		case "ii", "second", "2nd":
+			fields[0] = "2"
+		case "iii", "third", "3rd":
+			fields[0] = "3"
+		}
+	}
+	return strings.Join(fields, "")
+}
+
+
+// ResolveBook takes whatever the user typed for a book and gives back the name that is used in the database.
+// It knows about abbreviations (ie "Gen", "1 Cor", "Ps"), doesn't care about case, and handles singular/plural (ie "Psalm" or "Revelations")
+// If it can't find the book it will try to give a suggestion in the error, ie "did you mean Ecclesiastes?"
+func ResolveBook(name string) (string, error) {
+	// Clean up extra spaces, ie "1   John" or " Genesis "
+	cleaned := strings.Join(strings.Fields(name), " ")
+	normalized := normalizeBookName(cleaned)
+
+	if normalized == "" {
+		return "", fmt.Errorf("Please enter a book")
+	}
+
+	// Exact match on the name or one of the abbreviations
+	if book, ok := bookLookup[normalized]; ok {
+		return book, nil
+	}
+
+	// Singular/plural, ie "Psalm" -> "Psalms" or "Revelations" -> "Revelation"
+	// Not for short ones though, or "Jo" would turn into "Jos" (Joshua)
+	if len(normalized) >= 4 {
+		if book, ok := bookLookup[strings.TrimSuffix(normalized, "s")]; ok {
+			return book, nil
+		}
+		if book, ok := bookLookup[normalized+"s"]; ok {
+			return book, nil
+		}
+	}
+
+	// If it's the start of only one book, then it has to be that one. ie "Deut" or "Ephes"
+	var prefixMatches []string
+	for _, book := range allBooks {
+		if strings.HasPrefix(normalizeBookName(book), normalized) {
+			prefixMatches = append(prefixMatches, book)
+		}
+	}
+	if len(prefixMatches) == 1 {
+		return prefixMatches[0], nil
+	}
+
+	// Can't find it. If it's the start of a few books, list them, otherwise see if there is something close to suggest
+	if len(prefixMatches) > 1 {
+		return "", notFound("Can't find book \"%s\", did you mean one of %s?", cleaned, strings.Join(prefixMatches, ", "))
+	}
+	if suggestion := SuggestBook(cleaned); suggestion != "" {
+		return "", notFound("Can't find book \"%s\", did you mean %s?", cleaned, suggestion)
+	}
+
+	return "", notFound("Can't find book \"%s\"", cleaned)
+}
+
+
+// SuggestBook gives back the book that is closest to what was typed, or "" if nothing is very close.
+// It uses the edit distance (how many letters need to change) against all the names and abbreviations.
+func SuggestBook(name string) string {
+	normalized := normalizeBookName(name)
+
+	best := ""
+	bestDistance := -1
+	for alias, book := range bookLookup {
+		// Short abbreviations are too easy to be "close" to, so only use the longer ones for suggestions
+		if len(alias) < 4 {
+			continue
+		}
+
+		distance := editDistance(normalized, alias)
+		// Ties go to whichever book comes first in the bible, so the answer is always the same
+		if bestDistance == -1 || distance < bestDistance || (distance == bestDistance && bookIndex(book) < bookIndex(best)) {
+			best = book
+			bestDistance = distance
+		}
+	}
+
+	// Only suggest if it's close enough. A third of the letters being wrong is about as far as it makes sense
+	if bestDistance == -1 || bestDistance > max(2, len(normalized)/3) {
+		return ""
+	}
+
+	return best
+}
+
+
+// Gives back where the book is in the bible (Genesis is 0, Revelation is 65), or -1 if it's not a book
+func bookIndex(book string) int {
+	for i, b := range allBooks {
+		if b == book {
+			return i
+		}
+	}
+	return -1
+}
+
+
+// Levenshtein distance. The number of letters you have to add, remove or change to get from a to b
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+
+// Used by the lookup functions so that they can take any name for a book. If it can't be resolved,
+// it just gives back what it was given and the query will come back empty like it used to.
+func bookName(name string) string {
+	if book, err := ResolveBook(name); err == nil {
+		return book
+	}
+	return name
+}
+
+
+// FindBookGroup looks up a group of books by its name or one of its other names (ie "NT" or "gospels")
+func FindBookGroup(name string) (BookGroup, bool) {
+	normalized := normalizeBookName(name)
+	for _, group := range bookGroups {
+		if normalizeBookName(group.Name) == normalized {
+			return group, true
+		}
+		for _, alias := range group.Aliases {
+			if normalizeBookName(alias) == normalized {
+				return group, true
+			}
+		}
+	}
+	return BookGroup{}, false
+}
+
+
+// The whole group as a range, ie Gospels is Matthew 1:1 to the end of John
+func (g BookGroup) Range() VerseRange {
+	return VerseRange{Book: g.First, Chapter: 1, EndBook: g.Last}
+}
+
+
+// ParseScope works out what part of the bible something like --in is talking about. It can be a group (ie "NT", "Gospels"),
+// a book (ie "Romans"), or any reference (ie "Isaiah 40-66"). A few can be put together with ";", ie "Gospels; Acts"
+func ParseScope(scope string) ([]VerseRange, error) {
+	var ranges []VerseRange
+	for _, part := range strings.Split(scope, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if group, ok := FindBookGroup(part); ok {
+			ranges = append(ranges, group.Range())
+			continue
+		}
+
+		ref, err := ParseReference(part)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, ref.Ranges...)
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("Please give a book, group or range, ie --in NT or --in \"Isaiah 40-66\"")
+	}
+
+	return ranges, nil
+}
+
+
+// Prints all the groups of books (bible -s love --in list)
+func ListBookGroups() {
+	for _, group := range bookGroups {
+		name := group.Name
+		if len(group.Aliases) > 0 {
+			name += " (" + strings.Join(group.Aliases, ", ") + ")"
+		}
+		fmt.Printf("%-36s %s - %s\n", name, group.First, group.Last)
+	}
+}
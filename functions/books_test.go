@@ -0,0 +1,146 @@
+package functions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+
+func TestResolveBook(t *testing.T) {
+	tests := []struct {
+		input	string
+		want	string
+	}{
+		{"Genesis", "Genesis"},
+		{"genesis", "Genesis"},
+		{" Genesis ", "Genesis"},
+		{"Gen", "Genesis"},
+		{"Gen.", "Genesis"},
+		{"Deut", "Deuteronomy"},
+		{"Ephes", "Ephesians"},
+		{"Ps", "Psalms"},
+		{"Psalm", "Psalms"},
+		{"Revelations", "Revelation"},
+		{"1 Cor", "1 Corinthians"},
+		{"1cor", "1 Corinthians"},
+		{"I Cor.", "1 Corinthians"},
+		{"First Corinthians", "1 Corinthians"},
+		{"1   John", "1 John"},
+		{"iii john", "3 John"},
+		{"Song of Solomon", "Song of Solomon"},
+		{"John", "John"},
+	}
+
+	for _, test := range tests {
+		got, err := ResolveBook(test.input)
+		if err != nil {
+			t.Errorf("ResolveBook(%q) gave an error: %v", test.input, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ResolveBook(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+
+func TestResolveBookErrors(t *testing.T) {
+	tests := []struct {
+		input	string
+		message	string	// Something the error should have in it
+	}{
+		{"", "Please enter a book"},
+		{"Jhon", "did you mean John?"},
+		{"Ecclesiates", "did you mean Ecclesiastes?"},
+		// The start of more than one book lists them all
+		{"Jo", "did you mean one of"},
+		{"Qwerty", "Can't find book \"Qwerty\""},
+	}
+
+	for _, test := range tests {
+		got, err := ResolveBook(test.input)
+		if err == nil {
+			t.Errorf("ResolveBook(%q) = %q, want an error", test.input, got)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.message) {
+			t.Errorf("ResolveBook(%q) error = %q, want it to have %q in it", test.input, err, test.message)
+		}
+	}
+}
+
+
+func TestSuggestBook(t *testing.T) {
+	tests := []struct {
+		input	string
+		want	string
+	}{
+		{"Jhon", "John"},
+		{"Mathew", "Matthew"},
+		{"Genisis", "Genesis"},
+		{"Philipians", "Philippians"},
+		{"Levitcus", "Leviticus"},
+		// Nothing close enough
+		{"Qwerty", ""},
+		{"Banana bread", ""},
+	}
+
+	for _, test := range tests {
+		if got := SuggestBook(test.input); got != test.want {
+			t.Errorf("SuggestBook(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b	string
+		want	int
+	}{
+		{"", "", 0},
+		{"john", "john", 0},
+		{"jhon", "john", 2},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		scope	string
+		want	[]VerseRange
+	}{
+		{"NT", []VerseRange{{Book: "Matthew", Chapter: 1, EndBook: "Revelation"}}},
+		{"gospels", []VerseRange{{Book: "Matthew", Chapter: 1, EndBook: "John"}}},
+		{"the twelve", []VerseRange{{Book: "Hosea", Chapter: 1, EndBook: "Malachi"}}},
+		{"Romans", []VerseRange{{Book: "Romans", EndBook: "Romans"}}},
+		{"Isaiah 40-66", []VerseRange{{Book: "Isaiah", Chapter: 40, EndBook: "Isaiah", EndChapter: 66}}},
+		{"Gospels; Acts", []VerseRange{{Book: "Matthew", Chapter: 1, EndBook: "John"}, {Book: "Acts", EndBook: "Acts"}}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseScope(test.scope)
+		if err != nil {
+			t.Errorf("ParseScope(%q) gave an error: %v", test.scope, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseScope(%q) = %+v, want %+v", test.scope, got, test.want)
+		}
+	}
+
+	for _, scope := range []string{"", " ; ", "Gospells"} {
+		if got, err := ParseScope(scope); err == nil {
+			t.Errorf("ParseScope(%q) = %+v, want an error", scope, got)
+		}
+	}
+}
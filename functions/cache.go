@@ -0,0 +1,105 @@
+package functions
+
+import (
+	"io"
+	"os"
+	"fmt"
+	"strings"
+	"path/filepath"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+
+// GetCacheDir gives back the folder where the embedded database gets extracted to ($XDG_CACHE_HOME/bible, or ~/.cache/bible)
+func GetCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "bible"), nil
+}
+
+
+// CacheDatabase writes an embedded database to the cache folder, so sqlite can open it, and gives back the path to it.
+// It only gets written the first time. The name of the file has the checksum of the embedded database in it
+// (ie kjv-1a2b3c4d5e6f.db), so when a new version of the program has a different database it gets written again,
+// and the old one is removed.
+//
+// Nothing changes the file after it's written (the embedded translation is opened read only, see OpenTranslation),
+// so every run checks it still matches the checksum. If it doesn't (ie it got cut short or corrupted) it gets written again.
+func CacheDatabase(name string, data []byte) (string, error) {
+	dir, err := GetCacheDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])[:12]
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.db", name, checksum))
+
+	// Already there from last time
+	if cachedChecksum(path) == hex.EncodeToString(sum[:]) {
+		return path, nil
+	}
+
+	// Write it somewhere else first and then move it, so if two run at once (or it gets killed half way)
+	// nothing ever opens half a database
+	tmpFile, err := os.CreateTemp(dir, name+"-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return "", err
+	}
+
+	removeOldCaches(dir, name, path)
+
+	return path, nil
+}
+
+
+// Gives back the sha256 of a cached file, or "" if it can't be read
+func cachedChecksum(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+
+// Removes the databases that were extracted by older versions
+func removeOldCaches(dir string, name string, keep string) {
+	old, _ := filepath.Glob(filepath.Join(dir, name+"-*.db"))
+	for _, path := range old {
+		// Only the ones that look like name-<checksum>.db, just in case
+		checksum := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), name+"-"), ".db")
+		if path == keep || len(checksum) != 12 {
+			continue
+		}
+		os.Remove(path)
+		// sqlite might have left these next to it
+		os.Remove(path + "-journal")
+		os.Remove(path + "-wal")
+		os.Remove(path + "-shm")
+	}
+}
@@ -0,0 +1,83 @@
+package functions
+
+import (
+	"os"
+	"bytes"
+	"testing"
+	"path/filepath"
+)
+
+
+func TestCacheDatabase(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	data := []byte("not really a database")
+
+	path, err := CacheDatabase("test", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := filepath.Base(path); name != "test-b4910390f073.db" {
+		t.Errorf("cached as %s, want test-<checksum>.db", name)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Errorf("cached file = %q, want %q", got, data)
+	}
+
+	// The next run uses the same file
+	info, _ := os.Stat(path)
+	again, err := CacheDatabase("test", data)
+	if err != nil || again != path {
+		t.Fatalf("second CacheDatabase() = %s, %v, want %s", again, err, path)
+	}
+	if info2, _ := os.Stat(again); !os.SameFile(info, info2) {
+		t.Errorf("the cached file was written again when it didn't need to be")
+	}
+
+	// A new version has a different database, so the old one goes, but not other files that just start with the name
+	other := filepath.Join(filepath.Dir(path), "test-notes.db")
+	os.WriteFile(other, data, 0644)
+	newPath, err := CacheDatabase("test", []byte("a newer database"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newPath == path {
+		t.Errorf("a different database got the same name %s", path)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the old cached database is still there")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("removed %s, which isn't an old cached database", other)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp")); len(tmp) > 0 {
+		t.Errorf("left temp files behind: %v", tmp)
+	}
+}
+
+
+func TestCacheDatabaseRewritesBadCopies(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	data := []byte("not really a database")
+
+	path, err := CacheDatabase("test", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range [][]byte{
+		data[:5],						// Cut short
+		[]byte("not really a dAtabase"),	// Same size, but changed
+		{},
+	} {
+		if err := os.WriteFile(path, bad, 0644); err != nil {
+			t.Fatal(err)
+		}
+		again, err := CacheDatabase("test", data)
+		if err != nil || again != path {
+			t.Fatalf("CacheDatabase() = %s, %v, want %s", again, err, path)
+		}
+		if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+			t.Errorf("cached file with %q in it wasn't written again, it has %q", bad, got)
+		}
+	}
+}
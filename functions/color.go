@@ -0,0 +1,39 @@
+package functions
+
+import (
+	"os"
+	"regexp"
+	"golang.org/x/term"
+)
+
+
+// ANSI colors for the terminal
+const (
+	colorReset		= "\033[0m"
+	colorHighlight	= "\033[1;33m" // Bold yellow, for search matches
+)
+
+
+// Matches the ANSI color codes, so they can be left out when working out how wide some text is
+var ansiCodes = regexp.MustCompile("\033\\[[0-9;]*m")
+
+
+// IsTerminal is true if the output is going to a terminal, and not piped into another program or a file
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+
+// ColorEnabled is true if it's ok to print colors. Not if the output isn't a terminal, or if NO_COLOR is set (https://no-color.org)
+func ColorEnabled() bool {
+	return IsTerminal() && os.Getenv("NO_COLOR") == ""
+}
+
+
+// Wraps the text in a color, if colors are turned on
+func colorize(text string, color string) string {
+	if !ColorEnabled() || text == "" || color == "" {
+		return text
+	}
+	return color + text + colorReset
+}
@@ -0,0 +1,147 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"database/sql"
+)
+
+
+// A translation that has been opened, so it can be shown side by side with others (bible --compare KJV,WEB)
+type ParallelText struct {
+	Name	string
+	DB		*sql.DB
+}
+
+
+// The space between columns when translations are side by side
+const columnGap = 3
+
+// If the columns would be skinnier than this, the translations get stacked on top of each other instead
+const minColumnWidth = 30
+
+
+// OpenParallelTexts opens every translation in a list like "KJV,WEB". Remember to close them with CloseParallelTexts
+func OpenParallelTexts(list string) ([]ParallelText, error) {
+	var texts []ParallelText
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		t, err := FindTranslation(name)
+		if err != nil {
+			CloseParallelTexts(texts)
+			return nil, err
+		}
+
+		db, err := OpenTranslation(t.Name)
+		if err != nil {
+			CloseParallelTexts(texts)
+			return nil, err
+		}
+
+		texts = append(texts, ParallelText{Name: t.Name, DB: db})
+	}
+
+	if len(texts) < 2 {
+		CloseParallelTexts(texts)
+		return nil, fmt.Errorf("Please give at least two translations to compare, ie KJV,WEB")
+	}
+
+	return texts, nil
+}
+
+
+// Closes all the databases opened by OpenParallelTexts
+func CloseParallelTexts(texts []ParallelText) {
+	for _, t := range texts {
+		CloseDatabase(t.DB)
+	}
+}
+
+
+// PrintParallelVerse prints the same verse from every translation. The verse is looked up by book, chapter and verse
+// (not id) in each one, just in case a translation numbers things a bit differently.
+func PrintParallelVerse(texts []ParallelText, verse Bible) {
+	var names []string
+	var verseTexts []string
+	for _, t := range texts {
+		names = append(names, t.Name)
+
+		var text string
+		err := t.DB.QueryRow("SELECT text FROM bible WHERE bookName = ? AND chapter = ? AND verse = ?", verse.BookName, verse.Chapter, verse.Verse).Scan(&text)
+		if err != nil {
+			text = "(not in this translation)"
+		}
+		verseTexts = append(verseTexts, text)
+	}
+
+	fmt.Printf("%s %d:%d\n", verse.BookName, verse.Chapter, verse.Verse)
+
+	width := termWidth()
+	columnWidth := (width - columnGap*(len(texts)-1)) / len(texts)
+
+	// Not enough room for columns, so stack them
+	if columnWidth < minColumnWidth {
+		for i := range texts {
+			WordWrap(names[i] + ": " + verseTexts[i])
+		}
+		fmt.Printf("\n")
+		return
+	}
+
+	// Wrap every translation to fit in its column
+	var columns [][]string
+	rows := 0
+	for _, text := range verseTexts {
+		lines := WrapText(text, columnWidth)
+		columns = append(columns, lines)
+		rows = max(rows, len(lines))
+	}
+
+	// The names go across the top, then the text line by line
+	printColumns(names, columnWidth)
+	for row := 0; row < rows; row++ {
+		var cells []string
+		for _, lines := range columns {
+			if row < len(lines) {
+				cells = append(cells, lines[row])
+			} else {
+				cells = append(cells, "")
+			}
+		}
+		printColumns(cells, columnWidth)
+	}
+	fmt.Printf("\n")
+}
+
+
+// PrintParallelRange prints every verse in a range side by side. The first translation decides which verses are in the range.
+func PrintParallelRange(texts []ParallelText, r VerseRange) error {
+	verses, err := GetVersesInRange(texts[0].DB, r)
+	if err != nil {
+		return err
+	}
+
+	for _, verse := range verses {
+		PrintParallelVerse(texts, verse)
+	}
+	return nil
+}
+
+
+// Prints one line of columns, padding each one out to the column width
+func printColumns(cells []string, columnWidth int) {
+	var line string
+	for i, cell := range cells {
+		// No need to pad the last one, that just leaves spaces at the end of the line
+		if i == len(cells)-1 {
+			line += cell
+			break
+		}
+		line += cell + strings.Repeat(" ", max(columnWidth-textWidth(cell), 0)+columnGap)
+	}
+	fmt.Println(strings.TrimRight(line, " "))
+}
@@ -0,0 +1,135 @@
+package functions
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"path/filepath"
+)
+
+
+// Runs print and gives back everything it wrote to stdout
+func captureOutput(t *testing.T, print func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = old }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	print()
+	w.Close()
+	return <-done
+}
+
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text	string
+		width	int
+		want	[]string
+	}{
+		{"In the beginning God created the heaven and the earth.", 20, []string{"In the beginning God", "created the heaven", "and the earth."}},
+		{"In the beginning", 80, []string{"In the beginning"}},
+		// A word longer than the column still gets its own line rather than being cut
+		{"Mahershalalhashbaz was his name", 10, []string{"Mahershalalhashbaz", "was his", "name"}},
+		// Counts letters, not bytes
+		{"Ἐν ἀρχῇ ἦν ὁ λόγος", 8, []string{"Ἐν ἀρχῇ", "ἦν ὁ", "λόγος"}},
+		{"", 10, []string{""}},
+	}
+
+	for _, test := range tests {
+		got := WrapText(test.text, test.width)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("WrapText(%q, %d) = %q, want %q", test.text, test.width, got, test.want)
+		}
+		for _, line := range got {
+			if textWidth(line) > test.width && !strings.Contains(line, "Mahershalalhashbaz") {
+				t.Errorf("WrapText(%q, %d) has a line that's too long: %q", test.text, test.width, line)
+			}
+		}
+	}
+}
+
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		text	string
+		want	int
+	}{
+		{"love", 4},
+		{"ἀγάπη", 5},
+		// The colors don't take up any room
+		{colorHighlight + "love" + colorReset + " one another", 16},
+	}
+
+	for _, test := range tests {
+		if got := textWidth(test.text); got != test.want {
+			t.Errorf("textWidth(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
+
+
+func TestOpenParallelTexts(t *testing.T) {
+	dir := useTempTranslations(t)
+	writeTestBible(t, filepath.Join(dir, "web.db"))
+
+	texts, err := OpenParallelTexts("kjv, web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	CloseParallelTexts(texts)
+	if len(texts) != 2 || texts[0].Name != "KJV" || texts[1].Name != "WEB" {
+		t.Errorf("OpenParallelTexts(\"kjv, web\") = %+v", texts)
+	}
+
+	for _, list := range []string{"KJV", "KJV,", "KJV,NIV", ""} {
+		if texts, err := OpenParallelTexts(list); err == nil {
+			CloseParallelTexts(texts)
+			t.Errorf("OpenParallelTexts(%q) should give an error", list)
+		}
+	}
+}
+
+
+func TestPrintParallelVerse(t *testing.T) {
+	dir := useTempTranslations(t)
+	web := writeTestBible(t, filepath.Join(dir, "web.db"))
+	web.Exec("UPDATE bible SET text = 'In the beginning was the Word, and the Word was with God, and the Word was God.' WHERE id = 57")
+	web.Exec("DELETE FROM bible WHERE id = 58")
+
+	texts, err := OpenParallelTexts("KJV,WEB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer CloseParallelTexts(texts)
+
+	// Not a terminal, so it's 79 wide, which makes the columns 38
+	out := captureOutput(t, func() {
+		PrintParallelRange(texts, VerseRange{Book: "John", Chapter: 1, Verse: 1, EndBook: "John", EndChapter: 1, EndVerse: 2})
+	})
+	gap := strings.Repeat(" ", 38+columnGap)
+	want := "John 1:1\n" +
+		"KJV" + gap[3:] + "WEB\n" +
+		"John 1:1 text" + gap[13:] + "In the beginning was the Word, and the\n" +
+		gap + "Word was with God, and the Word was\n" +
+		gap + "God.\n" +
+		"\n" +
+		"John 1:2\n" +
+		"KJV" + gap[3:] + "WEB\n" +
+		"John 1:2 text" + gap[13:] + "(not in this translation)\n" +
+		"\n"
+	if out != want {
+		t.Errorf("PrintParallelRange printed:\n%s\nwant:\n%s", out, want)
+	}
+}
@@ -0,0 +1,213 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"strings"
+	"strconv"
+	"database/sql"
+	"golang.org/x/term"
+)
+
+
+// The ways interactive mode can show verses. 'v' goes through them in this order
+//   verse ...... one verse at a time (the usual)
+//   chapter .... the whole chapter, one verse per line with its number
+//   paragraph .. a screenful of verses run together like a printed bible, with little verse numbers
+var displayModes = []string{"verse", "chapter", "paragraph"}
+
+
+// Superscript digits, for the verse numbers in paragraph mode
+var superscriptDigits = []rune("⁰¹²³⁴⁵⁶⁷⁸⁹")
+
+
+// ParseDisplayMode checks that a display mode exists. It can be shortened, ie "p" for paragraph
+func ParseDisplayMode(name string) (string, error) {
+	name = strings.ToLower(name)
+	for _, mode := range displayModes {
+		if name != "" && strings.HasPrefix(mode, name) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown display mode \"%s\", use one of %s", name, strings.Join(displayModes, ", "))
+}
+
+
+// NextDisplayMode gives back the mode after this one, going back to the start after the last one
+func NextDisplayMode(mode string) string {
+	for i, m := range displayModes {
+		if m == mode {
+			return displayModes[(i+1)%len(displayModes)]
+		}
+	}
+	return displayModes[0]
+}
+
+
+// ChapterIdRange gives back the ids of the first and last verse in the chapter that a verse is in
+func ChapterIdRange(db *sql.DB, id int) (int, int, error) {
+	verse, err := LookupVerseId(db, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	return GetIdRange(db, VerseRange{Book: verse.BookName, Chapter: verse.Chapter, EndChapter: verse.Chapter})
+}
+
+
+// chapterVerses gives back every verse in the chapter that a verse is in
+func chapterVerses(db *sql.DB, id int) ([]Bible, error) {
+	verse, err := LookupVerseId(db, id)
+	if err != nil {
+		return nil, err
+	}
+	return GetVersesInRange(db, VerseRange{Book: verse.BookName, Chapter: verse.Chapter, EndChapter: verse.Chapter})
+}
+
+
+// PrintChapter prints the whole chapter that a verse is in, with the verse numbers down the side.
+// It gives back the ids of the first and last verse, so n and p know where the next and previous chapters are.
+func PrintChapter(db *sql.DB, id int) (int, int) {
+	verses, err := chapterVerses(db, id)
+	if err != nil {
+		fmt.Println(err)
+		return id, id
+	}
+
+	data := cachedSaveData()
+	fmt.Printf("%s Chapter %d\n\n", verses[0].BookName, verses[0].Chapter)
+	for _, verse := range verses {
+		number := fmt.Sprintf("%3d ", verse.Verse)
+		color := ""
+		if i := data.FindHighlight(verse.ID); i >= 0 {
+			color = highlightColors[data.CategoryColor(data.Highlights[i].Category)]
+		}
+
+		// The text hangs off the verse number, so the numbers stand out down the side
+		for i, line := range WrapText(verse.Text, termWidth()-len(number)) {
+			if i > 0 {
+				number = strings.Repeat(" ", len(number))
+			}
+			fmt.Println(number + colorize(line, color))
+		}
+		PrintVerseNotes(verse.ID)
+	}
+
+	return verses[0].ID, verses[len(verses)-1].ID
+}
+
+
+// PrintParagraph prints as many verses as fit on the screen starting at id, run together as a paragraph.
+// It stops at the end of the chapter. It gives back the id of the last verse printed, so n knows where to carry on from.
+func PrintParagraph(db *sql.DB, id int) int {
+	verses, err := chapterVerses(db, id)
+	if err != nil {
+		fmt.Println(err)
+		return id
+	}
+
+	// Only from id on
+	for len(verses) > 0 && verses[0].ID < id {
+		verses = verses[1:]
+	}
+	count := paragraphFits(verses, screenLines())
+	verses = verses[:count]
+
+	first := verses[0]
+	last := verses[len(verses)-1]
+	fmt.Printf("%s %d:%d-%d\n\n", first.BookName, first.Chapter, first.Verse, last.Verse)
+	fmt.Println(strings.Join(WrapText(paragraphText(verses), termWidth()), "\n"))
+
+	// Notes go after the paragraph, there isn't anywhere in the middle of it to put them
+	for _, verse := range verses {
+		PrintVerseNotes(verse.ID)
+	}
+
+	return last.ID
+}
+
+
+// PreviousParagraph works out where the screen before the one starting at id starts, for p in paragraph mode.
+// If id is the start of a chapter, it's the last screenful of the chapter before.
+func PreviousParagraph(db *sql.DB, id int) int {
+	if id <= 1 {
+		return 1
+	}
+
+	verses, err := chapterVerses(db, id-1)
+	if err != nil {
+		return id
+	}
+	for len(verses) > 0 && verses[len(verses)-1].ID >= id {
+		verses = verses[:len(verses)-1]
+	}
+
+	// Go backwards adding verses until the screen is full
+	start := len(verses) - 1
+	for start > 0 && paragraphFits(verses[start-1:], screenLines()) == len(verses)-start+1 {
+		start--
+	}
+	return verses[start].ID
+}
+
+
+// How many of the verses (from the start) fit in lines lines as a paragraph. Always at least one, even if it's a long one
+func paragraphFits(verses []Bible, lines int) int {
+	width := termWidth()
+	for count := 2; count <= len(verses); count++ {
+		if len(WrapText(paragraphText(verses[:count]), width)) > lines {
+			return count - 1
+		}
+	}
+	return len(verses)
+}
+
+
+// The verses run together, each starting with its number. Highlighted verses are in their color
+func paragraphText(verses []Bible) string {
+	data := cachedSaveData()
+	var parts []string
+	for _, verse := range verses {
+		text := verse.Text
+		if i := data.FindHighlight(verse.ID); i >= 0 {
+			text = colorize(text, highlightColors[data.CategoryColor(data.Highlights[i].Category)])
+		}
+		parts = append(parts, verseNumber(verse.Verse)+text)
+	}
+	return strings.Join(parts, " ")
+}
+
+
+// The verse number for paragraph mode. Superscript if the terminal can show it, otherwise in brackets, ie [16]
+func verseNumber(n int) string {
+	if !unicodeTerminal() {
+		return "[" + strconv.Itoa(n) + "] "
+	}
+
+	var number []rune
+	for _, digit := range strconv.Itoa(n) {
+		number = append(number, superscriptDigits[digit-'0'])
+	}
+	return string(number)
+}
+
+
+// Whether the terminal is set up for unicode. Going by the locale is the best that can be done
+func unicodeTerminal() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value = strings.ToUpper(value)
+			return strings.Contains(value, "UTF-8") || strings.Contains(value, "UTF8")
+		}
+	}
+	return false
+}
+
+
+// How many lines there are for a paragraph. The reference above it and the prompt under it need some room
+func screenLines() int {
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height <= 0 {
+		height = 24
+	}
+	return max(height-5, 3)
+}
@@ -0,0 +1,120 @@
+package functions
+
+import (
+	"strings"
+	"testing"
+)
+
+
+func TestParseDisplayMode(t *testing.T) {
+	tests := map[string]string{
+		"verse": "verse",
+		"Chapter": "chapter",
+		"p": "paragraph",
+		"ch": "chapter",
+	}
+	for name, want := range tests {
+		if got, err := ParseDisplayMode(name); err != nil || got != want {
+			t.Errorf("ParseDisplayMode(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	for _, name := range []string{"", "page", "verses"} {
+		if got, err := ParseDisplayMode(name); err == nil {
+			t.Errorf("ParseDisplayMode(%q) = %q, want an error", name, got)
+		}
+	}
+
+	mode := "verse"
+	var modes []string
+	for range displayModes {
+		mode = NextDisplayMode(mode)
+		modes = append(modes, mode)
+	}
+	if strings.Join(modes, " ") != "chapter paragraph verse" {
+		t.Errorf("pressing v goes through %v", modes)
+	}
+}
+
+
+func TestVerseNumber(t *testing.T) {
+	tests := []struct {
+		lcAll	string
+		lang	string
+		want	string
+	}{
+		{"", "en_US.UTF-8", "¹⁶"},
+		{"", "en_US.utf8", "¹⁶"},
+		{"C", "en_US.UTF-8", "[16] "},
+		{"", "", "[16] "},
+	}
+
+	for _, test := range tests {
+		t.Setenv("LC_ALL", test.lcAll)
+		t.Setenv("LC_CTYPE", "")
+		t.Setenv("LANG", test.lang)
+		if got := verseNumber(16); got != test.want {
+			t.Errorf("verseNumber(16) with LC_ALL=%q LANG=%q = %q, want %q", test.lcAll, test.lang, got, test.want)
+		}
+	}
+}
+
+
+func TestPrintChapter(t *testing.T) {
+	useTempUserDb(t)
+	db := newTestBibleDb(t)
+
+	if first, last, err := ChapterIdRange(db, 150); err != nil || first != 133 || last != 168 {
+		t.Errorf("ChapterIdRange(John 3:18) = %d, %d, %v, want 133, 168", first, last, err)
+	}
+
+	var first, last int
+	out := captureOutput(t, func() { first, last = PrintChapter(db, 180) })
+	if first != 169 || last != 193 {
+		t.Errorf("PrintChapter(Jude 1:12) = %d, %d, want 169, 193", first, last)
+	}
+	lines := strings.Split(out, "\n")
+	if lines[0] != "Jude Chapter 1" || lines[2] != "  1 Jude 1:1 text" || lines[26] != " 25 Jude 1:25 text" {
+		t.Errorf("PrintChapter(Jude 1:12) printed:\n%s", out)
+	}
+}
+
+
+func TestParagraphs(t *testing.T) {
+	useTempUserDb(t)
+	t.Setenv("LC_ALL", "C")
+	db := newTestBibleDb(t)
+	db.Exec("UPDATE bible SET text = text || ', and a bit more so that the verse takes up most of a line' WHERE book = 43 AND chapter = 1")
+
+	// Not a terminal, so it's 79 wide and there are 19 lines for the paragraph. John 1 doesn't fit all at once
+	var last int
+	out := captureOutput(t, func() { last = PrintParagraph(db, 57) })
+	if last <= 57 || last >= 107 {
+		t.Fatalf("PrintParagraph(John 1:1) stopped at %d, want part of John 1", last)
+	}
+	if !strings.HasPrefix(out, "John 1:1-") || !strings.Contains(out, "[1] John 1:1 text, and a bit more") {
+		t.Errorf("PrintParagraph(John 1:1) printed:\n%s", out)
+	}
+	if lines := strings.Count(out, "\n"); lines > 2+screenLines() {
+		t.Errorf("PrintParagraph(John 1:1) printed %d lines, that doesn't fit on the screen", lines)
+	}
+
+	// It stops at the end of the chapter
+	captureOutput(t, func() { last = PrintParagraph(db, 100) })
+	if last != 107 {
+		t.Errorf("PrintParagraph(John 1:44) stopped at %d, want 107 (the end of John 1)", last)
+	}
+
+	// Going back a screen from the start of John 2 gives a screen that ends at the end of John 1
+	start := PreviousParagraph(db, 108)
+	if start <= 57 || start > 107 {
+		t.Fatalf("PreviousParagraph(John 2:1) = %d, want somewhere in John 1", start)
+	}
+	captureOutput(t, func() { last = PrintParagraph(db, start) })
+	if last != 107 {
+		t.Errorf("PrintParagraph(%d) stopped at %d, want 107", start, last)
+	}
+	if PreviousParagraph(db, 58) != 57 || PreviousParagraph(db, 1) != 1 {
+		t.Errorf("PreviousParagraph() at the start should be the first verse")
+	}
+}
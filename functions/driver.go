@@ -0,0 +1,52 @@
+package functions
+
+import (
+	"sync"
+	"regexp"
+	"database/sql"
+	"github.com/mattn/go-sqlite3"
+)
+
+
+// The name of the sqlite driver that has our extra functions in it. Use this instead of "sqlite3" with sql.Open
+const DriverName = "sqlite3_bible"
+
+
+// Compiled regular expressions, so a search doesn't compile the same pattern again for every verse
+var regexCache sync.Map
+
+
+func init() {
+	// Same as the normal sqlite3 driver, but with a regexp function so "text REGEXP ?" works in queries
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", regexpMatch, true)
+		},
+	})
+}
+
+
+// This is what sqlite calls for "text REGEXP pattern". Note that sqlite gives the pattern first.
+func regexpMatch(pattern string, text string) (bool, error) {
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(text), nil
+}
+
+
+// Compiles a search pattern, or gets it from the cache if it has been compiled already.
+// Searches don't care about case, same as the other searches. Put (?-i) at the start to make it care.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
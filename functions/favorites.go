@@ -0,0 +1,206 @@
+package functions
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"strings"
+	"database/sql"
+)
+
+
+// The extra things about a favorite: which collections (tags) it's in, a note, and when it was added.
+// The favorites themselves are still just the ids in SaveData.Favorites, so older data files still work.
+type FavoriteDetail struct {
+	ID		int			`json:"id"`
+	Tags	[]string	`json:"tags,omitempty"`
+	Note	string		`json:"note,omitempty"`
+	Added	time.Time	`json:"added"`
+}
+
+
+// Gives back the details for a favorite, adding empty ones if it doesn't have any yet
+func (sd *SaveData) favoriteDetail(id int) *FavoriteDetail {
+	for i := range sd.FavoriteDetails {
+		if sd.FavoriteDetails[i].ID == id {
+			return &sd.FavoriteDetails[i]
+		}
+	}
+	sd.FavoriteDetails = append(sd.FavoriteDetails, FavoriteDetail{ID: id})
+	return &sd.FavoriteDetails[len(sd.FavoriteDetails)-1]
+}
+
+
+// GetFavoriteDetail gives back the details for a favorite (empty if it doesn't have any)
+func (sd *SaveData) GetFavoriteDetail(id int) FavoriteDetail {
+	for _, detail := range sd.FavoriteDetails {
+		if detail.ID == id {
+			return detail
+		}
+	}
+	return FavoriteDetail{ID: id}
+}
+
+
+// AddFavorite adds a verse to the favorites, if it isn't already
+func (ut *UserTx) AddFavorite(id int) error {
+	_, err := ut.tx.Exec("INSERT OR IGNORE INTO favorites (verse_id, added) VALUES (?, ?)", id, formatTime(time.Now()))
+	return err
+}
+
+
+// RemoveFavorite takes a verse out of the favorites. The tags and note go with it
+func (ut *UserTx) RemoveFavorite(id int) error {
+	if _, err := ut.tx.Exec("DELETE FROM favorite_tags WHERE verse_id = ?", id); err != nil {
+		return err
+	}
+	_, err := ut.tx.Exec("DELETE FROM favorites WHERE verse_id = ?", id)
+	return err
+}
+
+
+// ContainsFavorite checks if a verse is in the favorites
+func (ut *UserTx) ContainsFavorite(id int) (bool, error) {
+	var count int
+	err := ut.tx.QueryRow("SELECT COUNT(*) FROM favorites WHERE verse_id = ?", id).Scan(&count)
+	return count > 0, err
+}
+
+
+// TagFavorite puts a favorite in a collection. It gets added to the favorites if it isn't already
+func (ut *UserTx) TagFavorite(id int, tag string) error {
+	if err := ut.AddFavorite(id); err != nil {
+		return err
+	}
+	tag = tagName(tag)
+	if tag == "" {
+		return nil
+	}
+	_, err := ut.tx.Exec("INSERT OR IGNORE INTO favorite_tags (verse_id, tag) VALUES (?, ?)", id, tag)
+	return err
+}
+
+
+// UntagFavorite takes a favorite out of a collection. It's still a favorite after. Gives back false if it wasn't in it
+func (ut *UserTx) UntagFavorite(id int, tag string) (bool, error) {
+	return ut.execChanged("DELETE FROM favorite_tags WHERE verse_id = ? AND tag = ?", id, tagName(tag))
+}
+
+
+// SetFavoriteNote sets the note on a favorite. An empty note removes it
+func (ut *UserTx) SetFavoriteNote(id int, note string) error {
+	if err := ut.AddFavorite(id); err != nil {
+		return err
+	}
+	_, err := ut.tx.Exec("UPDATE favorites SET note = ? WHERE verse_id = ?", strings.TrimSpace(note), id)
+	return err
+}
+
+
+// FavoriteTags gives back every collection, and how many favorites are in it
+func (sd *SaveData) FavoriteTags() map[string]int {
+	tags := make(map[string]int)
+	for _, id := range sd.Favorites {
+		for _, tag := range sd.GetFavoriteDetail(id).Tags {
+			tags[tag]++
+		}
+	}
+	return tags
+}
+
+
+// Tags are lower case with dashes instead of spaces, ie "Memory 2026" is memory-2026
+func tagName(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+}
+
+
+// This lists your favorites. (ie. bible -f). If tag isn't empty, only the ones in that collection (bible -f --tag comfort)
+func ListFavorites(db *sql.DB, tag string) {
+	saveData, err := LoadSaveData()
+	if err != nil {
+		fmt.Println("Error loading data:", err)
+		return
+	}
+
+	tag = tagName(tag)
+	found := false
+	for _, id := range saveData.Favorites {
+		detail := saveData.GetFavoriteDetail(id)
+		if tag != "" && !containsString(detail.Tags, tag) {
+			continue
+		}
+		found = true
+
+		// Get info from id
+		verse := GetVerseFromId(db, id)
+
+		//Print Verse
+		if !IsTextOutput() {
+			EmitVerse(verse)
+			continue
+		}
+		PrintVerseText(verse)
+		printFavoriteDetail(detail)
+		fmt.Println()
+	}
+
+	if !found && IsTextOutput() {
+		if tag != "" {
+			fmt.Printf("No favorites tagged %s\n", tag)
+			printFavoriteTags(saveData)
+		} else {
+			fmt.Println("No favorites yet. Use 'f' in interactive mode, or bible favorite add John 3:16")
+		}
+	}
+}
+
+
+// Prints the tags, note and date under a favorite
+func printFavoriteDetail(detail FavoriteDetail) {
+	var info []string
+	if len(detail.Tags) > 0 {
+		info = append(info, "Tags: "+strings.Join(detail.Tags, ", "))
+	}
+	if !detail.Added.IsZero() {
+		info = append(info, "Added "+detail.Added.Local().Format("2006-01-02"))
+	}
+	if len(info) > 0 {
+		fmt.Println(colorize("  "+strings.Join(info, " | "), colorNote))
+	}
+	if detail.Note != "" {
+		for _, line := range WrapText(detail.Note, termWidth()-4) {
+			fmt.Println(colorize("  | ", colorNote) + line)
+		}
+	}
+}
+
+
+// Prints every collection with how many favorites are in it
+func printFavoriteTags(saveData *SaveData) {
+	tags := saveData.FavoriteTags()
+	if len(tags) == 0 {
+		fmt.Println("No tags yet, ie bible favorite add John 3:16 --tag comfort")
+		return
+	}
+
+	var names []string
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+	for _, tag := range names {
+		fmt.Printf("%-20s %d\n", tag, tags[tag])
+	}
+}
+
+
+// ListFavoriteTags prints every collection with how many favorites are in it (bible favorite tags)
+func ListFavoriteTags() {
+	saveData, err := LoadSaveData()
+	if err != nil {
+		fmt.Println("Error loading data:", err)
+		return
+	}
+	printFavoriteTags(saveData)
+}
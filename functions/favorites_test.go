@@ -0,0 +1,99 @@
+package functions
+
+import (
+	"reflect"
+	"testing"
+)
+
+
+func TestTagFavorite(t *testing.T) {
+	useTempUserDb(t)
+	data := updateAndLoad(t, func(ut *UserTx) error {
+		ut.TagFavorite(148, "Comfort")
+		ut.TagFavorite(148, "Memory 2026")
+		ut.TagFavorite(148, "comfort")
+		return ut.TagFavorite(57, "")
+	})
+
+	// Tagging something adds it to the favorites
+	if !reflect.DeepEqual(data.Favorites, []int{57, 148}) {
+		t.Errorf("Favorites = %v, want 148 and 57", data.Favorites)
+	}
+	detail := data.GetFavoriteDetail(148)
+	if !reflect.DeepEqual(detail.Tags, []string{"comfort", "memory-2026"}) || detail.Added.IsZero() {
+		t.Errorf("GetFavoriteDetail(148) = %+v, want it tagged comfort and memory-2026 with the date it was added", detail)
+	}
+	if tags := data.GetFavoriteDetail(57).Tags; len(tags) != 0 {
+		t.Errorf("an empty tag got added: %v", tags)
+	}
+
+	var first, second bool
+	data = updateAndLoad(t, func(ut *UserTx) error {
+		first, _ = ut.UntagFavorite(148, "COMFORT")
+		second, _ = ut.UntagFavorite(148, "comfort")
+		return ut.SetFavoriteNote(148, "  For when it's hard  ")
+	})
+	if !first || second {
+		t.Errorf("UntagFavorite(148, comfort) should only work once")
+	}
+	if !data.ContainsFavorite(148) {
+		t.Errorf("taking the tag off removed the favorite")
+	}
+	if note := data.GetFavoriteDetail(148).Note; note != "For when it's hard" {
+		t.Errorf("note = %q", note)
+	}
+
+	// Removing the favorite removes its tags and note too
+	data = updateAndLoad(t, func(ut *UserTx) error {
+		if err := ut.RemoveFavorite(148); err != nil {
+			return err
+		}
+		return ut.AddFavorite(148)
+	})
+	if detail := data.GetFavoriteDetail(148); len(detail.Tags) != 0 || detail.Note != "" {
+		t.Errorf("after removing 148 it still has %+v", detail)
+	}
+}
+
+
+func TestFavoriteTags(t *testing.T) {
+	useTempUserDb(t)
+	data := updateAndLoad(t, func(ut *UserTx) error {
+		ut.TagFavorite(148, "comfort")
+		ut.TagFavorite(57, "comfort")
+		ut.TagFavorite(57, "creation")
+		return ut.AddFavorite(5)
+	})
+
+	if got, want := data.FavoriteTags(), map[string]int{"comfort": 2, "creation": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FavoriteTags() = %v, want %v", got, want)
+	}
+}
+
+
+func TestListFavorites(t *testing.T) {
+	useTempUserDb(t)
+	db := newTestBibleDb(t)
+
+	err := UpdateUserData(func(ut *UserTx) error {
+		if err := ut.TagFavorite(148, "comfort"); err != nil {
+			return err
+		}
+		return ut.AddFavorite(57)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := data.GetFavoriteDetail(148).Added.Local().Format("2006-01-02")
+
+	if out := captureOutput(t, func() { ListFavorites(db, "Comfort") }); out != "John 3:16\nJohn 3:16 text\n  Tags: comfort | Added "+added+"\n\n" {
+		t.Errorf("ListFavorites(comfort) printed %q", out)
+	}
+	if out := captureOutput(t, func() { ListFavorites(db, "peace") }); out != "No favorites tagged peace\ncomfort              1\n" {
+		t.Errorf("ListFavorites(peace) printed %q", out)
+	}
+}
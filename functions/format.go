@@ -0,0 +1,127 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"strconv"
+	"strings"
+	"encoding/csv"
+	"encoding/json"
+)
+
+
+// All the output formats (--format). text is the normal way of printing, the others are for scripts
+var outputFormats = []string{"text", "json", "jsonl", "csv", "md"}
+
+
+// The format that verses get printed in
+var outputFormat = "text"
+
+// For json, the verses are saved up and printed as one array at the end by FlushOutput
+var jsonVerses = []Bible{}
+
+// For csv, the header only gets printed before the first verse
+var csvWriter *csv.Writer
+
+// Set once EmitJSON has printed something, so FlushOutput doesn't add an empty verse array after it
+var jsonEmitted bool
+
+
+// SetOutputFormat changes how verses get printed (bible --format json)
+func SetOutputFormat(format string) error {
+	format = strings.ToLower(format)
+	for _, f := range outputFormats {
+		if f == format {
+			outputFormat = format
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown format \"%s\", use one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+
+// IsTextOutput is true if verses are being printed the normal way, and not as json/csv/etc
+func IsTextOutput() bool {
+	return outputFormat == "text"
+}
+
+
+// GetOutputFormat gives back the format that was set with SetOutputFormat
+func GetOutputFormat() string {
+	return outputFormat
+}
+
+
+// EmitJSON prints something that isn't verses (ie the reading history or stats) as json.
+// For jsonl it all goes on one line, otherwise it's indented like the verses are
+func EmitJSON(v any) {
+	var data []byte
+	if outputFormat == "jsonl" {
+		data, _ = json.Marshal(v)
+	} else {
+		data, _ = json.MarshalIndent(v, "", "  ")
+	}
+	fmt.Println(string(data))
+	jsonEmitted = true
+}
+
+
+// PrintMessage prints something that isn't a verse, ie "Chapters in John: 21" or an error. For json/csv/etc it goes
+// to stderr instead, so what comes out on stdout is still only the verses and scripts can read it
+func PrintMessage(format string, a ...any) {
+	if IsTextOutput() {
+		fmt.Printf(format, a...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, format, a...)
+}
+
+
+// EmitVerse prints a verse in whatever the output format is. Everything that prints verses should go through here.
+// The json/jsonl/csv formats always have the same fields: id, bookName, book, chapter, verse, text
+func EmitVerse(verse Bible) {
+	switch outputFormat {
+	case "json":
+		jsonVerses = append(jsonVerses, verse)
+	case "jsonl":
+		data, _ := json.Marshal(verse)
+		fmt.Println(string(data))
+	case "csv":
+		if csvWriter == nil {
+			csvWriter = csv.NewWriter(os.Stdout)
+			csvWriter.Write([]string{"id", "bookName", "book", "chapter", "verse", "text"})
+		}
+		csvWriter.Write([]string{
+			strconv.Itoa(verse.ID),
+			verse.BookName,
+			strconv.Itoa(verse.Book),
+			strconv.Itoa(verse.Chapter),
+			strconv.Itoa(verse.Verse),
+			verse.Text,
+		})
+	case "md":
+		fmt.Printf("**%s %d:%d** %s\n\n", verse.BookName, verse.Chapter, verse.Verse, verse.Text)
+	default:
+		PrintVerseText(verse)
+		fmt.Printf("\n")
+	}
+}
+
+
+// FlushOutput finishes off the output. json needs this to print the array, and csv needs it to flush.
+// main calls this at the end, so nothing else needs to worry about it.
+func FlushOutput() {
+	switch outputFormat {
+	case "json":
+		if jsonEmitted && len(jsonVerses) == 0 {
+			return
+		}
+		data, _ := json.MarshalIndent(jsonVerses, "", "  ")
+		fmt.Println(string(data))
+		jsonVerses = []Bible{}
+	case "csv":
+		if csvWriter != nil {
+			csvWriter.Flush()
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package functions
+
+import (
+	"testing"
+)
+
+
+// Prints with format for the rest of the test, and puts everything back after
+func useOutputFormat(t *testing.T, format string) {
+	t.Helper()
+	if err := SetOutputFormat(format); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		outputFormat = "text"
+		jsonVerses = []Bible{}
+		csvWriter = nil
+		jsonEmitted = false
+	})
+}
+
+
+func TestSetOutputFormat(t *testing.T) {
+	useOutputFormat(t, "JSON")
+	if outputFormat != "json" || IsTextOutput() {
+		t.Errorf("SetOutputFormat(\"JSON\") left the format as %q", outputFormat)
+	}
+
+	if err := SetOutputFormat("xml"); err == nil {
+		t.Errorf("SetOutputFormat(\"xml\") should give an error")
+	}
+	if outputFormat != "json" {
+		t.Errorf("A bad format shouldn't change the format, it's %q", outputFormat)
+	}
+}
+
+
+func TestEmitVerse(t *testing.T) {
+	verses := []Bible{
+		{ID: 148, BookName: "John", Book: 43, Chapter: 3, Verse: 16, Text: "For God so loved the world, that he gave"},
+		{ID: 149, BookName: "John", Book: 43, Chapter: 3, Verse: 17, Text: "For \"God\" sent not"},
+	}
+
+	tests := []struct {
+		format	string
+		want	string
+	}{
+		{"text", "John 3:16\nFor God so loved the world, that he gave\n\nJohn 3:17\nFor \"God\" sent not\n\n"},
+		{"json", `[
+  {
+    "id": 148,
+    "bookName": "John",
+    "book": 43,
+    "chapter": 3,
+    "verse": 16,
+    "text": "For God so loved the world, that he gave"
+  },
+  {
+    "id": 149,
+    "bookName": "John",
+    "book": 43,
+    "chapter": 3,
+    "verse": 17,
+    "text": "For \"God\" sent not"
+  }
+]
+`},
+		{"jsonl", `{"id":148,"bookName":"John","book":43,"chapter":3,"verse":16,"text":"For God so loved the world, that he gave"}
+{"id":149,"bookName":"John","book":43,"chapter":3,"verse":17,"text":"For \"God\" sent not"}
+`},
+		{"csv", `id,bookName,book,chapter,verse,text
+148,John,43,3,16,"For God so loved the world, that he gave"
+149,John,43,3,17,"For ""God"" sent not"
+`},
+		{"md", "**John 3:16** For God so loved the world, that he gave\n\n**John 3:17** For \"God\" sent not\n\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			useOutputFormat(t, test.format)
+			got := captureOutput(t, func() {
+				for _, verse := range verses {
+					EmitVerse(verse)
+				}
+				FlushOutput()
+			})
+			if got != test.want {
+				t.Errorf("--format %s printed:\n%s\nwant:\n%s", test.format, got, test.want)
+			}
+		})
+	}
+}
+
+
+func TestEmptyJsonOutput(t *testing.T) {
+	useOutputFormat(t, "json")
+	// Nothing found is still valid json
+	if got := captureOutput(t, FlushOutput); got != "[]\n" {
+		t.Errorf("--format json with no verses printed %q, want []", got)
+	}
+}
+
+
+func TestEmitJSON(t *testing.T) {
+	counts := []BookCount{{"John", 2}}
+
+	useOutputFormat(t, "json")
+	// Something that isn't verses doesn't get an empty verse array after it
+	got := captureOutput(t, func() {
+		EmitJSON(counts)
+		FlushOutput()
+	})
+	if want := "[\n  {\n    \"bookName\": \"John\",\n    \"count\": 2\n  }\n]\n"; got != want {
+		t.Errorf("EmitJSON() with json printed %q, want %q", got, want)
+	}
+
+	useOutputFormat(t, "jsonl")
+	if got := captureOutput(t, func() { EmitJSON(counts) }); got != "[{\"bookName\":\"John\",\"count\":2}]\n" {
+		t.Errorf("EmitJSON() with jsonl printed %q", got)
+	}
+}
+
+
+func TestPrintMessage(t *testing.T) {
+	useOutputFormat(t, "text")
+	if got := captureOutput(t, func() { PrintMessage("Chapters in %s: %d\n", "John", 21) }); got != "Chapters in John: 21\n" {
+		t.Errorf("PrintMessage() with text printed %q", got)
+	}
+
+	// Scripts reading the json shouldn't get it
+	useOutputFormat(t, "json")
+	if got := captureOutput(t, func() { PrintMessage("Chapters in %s: %d\n", "John", 21) }); got != "" {
+		t.Errorf("PrintMessage() with json printed %q on stdout, it should go to stderr", got)
+	}
+}
@@ -3,15 +3,15 @@ package functions
 import (
 	"os"
 	"fmt"
-	"log"
-	"time"
-	"bufio"
+	"io"
 	"io/ioutil"
 	"path/filepath"
 	"sort"
+	"time"
+	"sync"
 	"strings"
 	"strconv"
-	"math/rand"
+	"unicode/utf8"
 	"database/sql"
 	"encoding/json"
 	"golang.org/x/term"
@@ -19,13 +19,14 @@ import (
 
 
 // This struct is to reference the sql database
+// The json names are what --format json uses, so don't change them
 type Bible struct {
-	ID       	int
-	BookName	string
-	Book		int
-	Chapter  	int
-	Verse    	int
-	Text     	string
+	ID       	int		`json:"id"`
+	BookName	string	`json:"bookName"`
+	Book		int		`json:"book"`
+	Chapter  	int		`json:"chapter"`
+	Verse    	int		`json:"verse"`
+	Text     	string	`json:"text"`
 }
 
 
@@ -55,6 +56,32 @@ var allBooks = []string{
 }
 
 
+// A group of books that go together, ie the Gospels. They are always in a row, so a group is just a first and last book.
+type BookGroup struct {
+	Name		string
+	Aliases		[]string
+	First		string
+	Last		string
+}
+
+
+// The usual ways the books get grouped. Used for things like searching only in the Gospels (bible -s love --in Gospels)
+var bookGroups = []BookGroup{
+	{"Old Testament", []string{"OT"}, "Genesis", "Malachi"},
+	{"New Testament", []string{"NT"}, "Matthew", "Revelation"},
+	{"Law", []string{"Torah", "Pentateuch"}, "Genesis", "Deuteronomy"},
+	{"History", []string{"Historical"}, "Joshua", "Esther"},
+	{"Wisdom", []string{"Poetry", "Poetic"}, "Job", "Song of Solomon"},
+	{"Prophets", []string{"Prophecy"}, "Isaiah", "Malachi"},
+	{"Major Prophets", nil, "Isaiah", "Daniel"},
+	{"Minor Prophets", []string{"The Twelve"}, "Hosea", "Malachi"},
+	{"Gospels", nil, "Matthew", "John"},
+	{"Epistles", []string{"Letters"}, "Romans", "Jude"},
+	{"Pauline Epistles", []string{"Paul", "Pauline"}, "Romans", "Philemon"},
+	{"General Epistles", []string{"Catholic Epistles"}, "Hebrews", "Jude"},
+}
+
+
 // Print Verse
 // these are a string for a reason...I think beause random verse needs to return a []string, so it made it easier to do? because the bookname is a string,
 // And I wanted it to return a single array
@@ -62,26 +89,34 @@ func PrintVerse(db *sql.DB, book string, chapter string, verse string) {
 	chapterInt, _ := strconv.Atoi(chapter)
 	verseInt, _ := strconv.Atoi(verse)
 
-	var bibleVerse Bible
-	err := db.QueryRow("SELECT id, bookName, chapter, verse, text FROM bible where bookName = ? AND chapter = ? AND verse = ?", book, chapterInt, verseInt).Scan(&bibleVerse.ID, &bibleVerse.BookName, &bibleVerse.Chapter, &bibleVerse.Verse, &bibleVerse.Text)
+	bibleVerse, err := LookupVerse(db, book, chapterInt, verseInt)
 	if err != nil {
 		fmt.Printf("Can't find %s %s %s\n\n", book, chapter, verse)
 		return
 	}
-	fmt.Printf("%s %s:%s\n", book, chapter, verse)
-	WordWrap(bibleVerse.Text)
-	fmt.Printf("\n")
+	printBibleVerse(bibleVerse)
 }
 
 
-// Function to ask the user for input in interactive mode
+// This is what actually prints a verse, once it has been looked up. It goes through EmitVerse so --format works
+// Any notes on the verse get printed under it (only for text, json/csv/etc are just the verses)
+func printBibleVerse(bibleVerse Bible) {
+	EmitVerse(bibleVerse)
+	if IsTextOutput() {
+		PrintVerseNotes(bibleVerse.ID)
+	}
+}
+
+
+// Function to ask the user for input in interactive mode. The line can be edited, and it goes in the history (see readline.go)
+// If there is nothing left to read (ctrl-d, or the end of a piped file) it gives back "q", so interactive mode quits instead of asking forever
 func GetUserInput(prompt string) []string {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print(prompt)
-	bookChapterVerse, err := reader.ReadString('\n')
+	bookChapterVerse, err := ReadLine(prompt, true)
 	if err != nil {
-		fmt.Println("Error reading input:", err)
-		return []string{}
+		if err != io.EOF {
+			fmt.Println("Error reading input:", err)
+		}
+		return []string{"q"}
 	}
 
 	// Clean the white space (including the newline character)
@@ -93,84 +128,63 @@ func GetUserInput(prompt string) []string {
 
 // Prints help when you use ? or h in interactive mode
 func PrintInteractiveHelp() {
-	WordWrap("\nTo get to a specific verse just type in the verse, ie Genesis 1 1, or \"1 John\" 5 10\n")
+	WordWrap("\nTo get to a specific verse just type in the verse, ie Genesis 1 1, John 3:16, or 1 John 5:10\n")
+	WordWrap("Tab finishes book names, chapters and verses. Up and down go through what you typed before (even last time), and the line can be edited like a shell (ctrl-a, ctrl-e, ctrl-w, ctrl-u...)")
 	fmt.Println()
 	fmt.Println("Interactive Commands:")
-	fmt.Println("    b ......... bookmark")
+	fmt.Println("    b ......... bookmark (load or save, with a name if you want more than one)")
+	fmt.Println("    c ......... compare translations side by side (on/off)")
 	fmt.Println("    f ......... favorite")
-	fmt.Println("    n ......... next verse")
-	fmt.Println("    p ......... previous verse")
+	fmt.Println("    m ......... highlight (mark) in a category, ie promise, command, prophecy")
+	fmt.Println("    n ......... next verse (or chapter, or screen)")
+	fmt.Println("    p ......... previous verse (or chapter, or screen)")
 	fmt.Println("    r ......... random verse")
+	fmt.Println("    t ......... full screen reader, a chapter at a time (q to come back)")
+	fmt.Println("    v ......... show a verse, a chapter or a screen of paragraph at a time (v chapter to pick one)")
+	fmt.Println("    a ......... add or edit a note on this verse (a John 3:16-18 for a range)")
 	fmt.Println("    q ......... quit")
-	fmt.Println("    h or ? .... print this help usage\n")
+	fmt.Println("    h or ? .... print this help usage")
+	fmt.Println()
+	fmt.Println("When starting:")
+	fmt.Println("    r ......... random verse")
+	fmt.Println("    b ......... load a bookmark")
+	fmt.Println("    l or last . carry on from the last verse you read")
+	fmt.Println()
 }
 
 //  Takes the command from interactive mode (if it is more than a single character), returns the id of the verse to go to
 func ParseInteractiveCommand(db *sql.DB, split []string) int {
-	var bookName string
-	var chapter string
-	var verse string
-	var id int
-
-	// This is a special case for "Song of Solomon", which is the only 3 word book
-	if split[0][0] == '"' && split[0] == "\"Song" {
-		bookName = strings.Trim(split[0] + " " + split[1] + " " + split[2], "\"")
-		chapter = split[3]
-		verse = split[4]
-		id = GetIdOfVerse(db, bookName, chapter, verse)
-		return id
-	// This is a special case for 'Song of Solomon' (if single quotes are used)
-	} else if split[0][0] == '\'' && split[0] == "'Song" {
-		bookName = strings.Trim(split[0] + " " + split[1] + " " + split[2], "'")
-		chapter = split[3]
-		verse = split[4]
-		id = GetIdOfVerse(db, bookName, chapter, verse)
-		return id
-	// Detect if it is a numbered book ("1 John" etc)
-	} else if split[0][0] == '"' {
-		bookName = strings.Trim(split[0] + " " + split[1], "\"")
-		chapter = split[2]
-		verse = split[3]
-		id = GetIdOfVerse(db, bookName, chapter, verse)
-		return id
-	// This is just to catch if the user uses single quotes instead of double
-	} else if  split[0][0] == '\'' {
-		bookName = strings.Trim(split[0] + " " + split[1], "'")
-		chapter = split[2]
-		verse = split[3]
-		id = GetIdOfVerse(db, bookName, chapter, verse)
-		return id
-	// If not a numbered book, get the verse
-	} else if len(split) == 3 {
-		bookName = split[0]
-		chapter = split[1]
-		verse = split[2]
-		id = GetIdOfVerse(db, bookName, chapter, verse)
-		return id
+	ref, err := ParseReference(strings.Join(split, " "))
+	if err != nil {
+		fmt.Println(err)
+		return -1
 	}
 
-	return -1
+	// Only the start of the reference matters here. If it's a whole book or chapter, start at the first verse of it.
+	r := ref.Ranges[0]
+	chapter := max(r.Chapter, 1)
+	verse := max(r.Verse, 1)
 
+	return GetIdOfVerse(db, r.Book, strconv.Itoa(chapter), strconv.Itoa(verse))
 }
 
 
 // Get the id of a verse. Would be useful in interactive mode, so that then you could just go next or previous based on id.
-func GetIdOfVerse(db *sql.DB, bookName string, chapter string, verse string) int {
-	var id int
-	query := "SELECT id FROM bible WHERE bookName = ? AND chapter = ? AND verse = ?"
-	err := db.QueryRow(query, bookName, chapter, verse).Scan(&id)
+// Gives back -1 if it can't find it. Use LookupVerse to get the error instead.
+func GetIdOfVerse(db *sql.DB, book string, chapter string, verse string) int {
+	chapterInt, _ := strconv.Atoi(chapter)
+	verseInt, _ := strconv.Atoi(verse)
+	bibleVerse, err := LookupVerse(db, book, chapterInt, verseInt)
 	if err != nil {
-		fmt.Printf("Can't find %s %s %s\n\n", bookName, chapter, verse)
+		fmt.Printf("Can't find %s %s %s\n\n", book, chapter, verse)
 		return -1
 	}
 	
-	return id
+	return bibleVerse.ID
 }
 
 func GetVerseFromId(db *sql.DB, id int) Bible {
-	var verse Bible
-	query := "SELECT bookName, chapter, verse, text FROM bible where id = ?"
-	err := db.QueryRow(query, id).Scan(&verse.BookName, &verse.Chapter, &verse.Verse, &verse.Text)
+	verse, err := LookupVerseId(db, id)
 	if err != nil {
 		fmt.Printf("Can't get verse from id: %d\n", id)
 		fmt.Println(err)
@@ -183,89 +197,48 @@ func GetVerseFromId(db *sql.DB, id int) Bible {
 func RandomVerse(db *sql.DB) Passage {
 	var passage Passage
 
-	rand.Seed(time.Now().UnixNano())
-
-	// Get a random book
-	passage.BookName = allBooks[rand.Intn(66)]
-
-	// Get number of chapters in random book
-	chapters := GetAllChaptersInBook(db, passage.BookName)
-
-	// Get random chapter
-	// i think this needs the +1 to not try to pick chapter "0". needs to be 1-chapters It might be a bug?
-	passage.Chapter = strconv.Itoa(rand.Intn(chapters) + 1)
-
-	// Get number of verses in chapter
-	verses := GetAllVersesInChapter(db, passage.BookName, passage.Chapter)
+	verse, err := LookupRandomVerse(db)
+	if err != nil {
+		fmt.Println("Can't get a random verse: ", err)
+		return passage
+	}
 
-	// Get random verse
-	// I think this needs the plus 1 to not try to get verse "0". it might be a bug?
-	passage.Verse = strconv.Itoa(rand.Intn(verses) + 1)
+	passage.BookName = verse.BookName
+	passage.Chapter = strconv.Itoa(verse.Chapter)
+	passage.Verse = strconv.Itoa(verse.Verse)
 
 	return passage
 }
 
 
-// This gives the number of verses in a chapter
-func GetAllVersesInChapter(db *sql.DB, bookName string, chapter string) int {
-    var verses []int
-    query := "SELECT verse FROM bible WHERE bookName = ? AND chapter = ?"
-    rows, err := db.Query(query, bookName, chapter)
-    if err != nil {
-        log.Fatal(err)
-    }
-
-    defer rows.Close()
-
-    for rows.Next() {
-        var verse int
-        if err := rows.Scan(&verse); err != nil {
-            log.Fatal(err)
-        }
-        verses = append(verses, verse)
-    }
-
-    return len(verses)
-}
-
-
-// This gives number of chapters in a book
-func GetAllChaptersInBook(db *sql.DB, bookName string) int {
-	query := "SELECT chapter FROM bible WHERE bookName = ?"
-	rows, err := db.Query(query, bookName)
+// This gives the number of verses in a chapter (0 if it doesn't exist). Use CountVerses to get the error instead.
+func GetAllVersesInChapter(db *sql.DB, book string, chapter string) int {
+	chapterInt, _ := strconv.Atoi(chapter)
+	verses, err := CountVerses(db, book, chapterInt)
 	if err != nil {
-		log.Fatal(err)
+		PrintMessage("%s\n", err)
 	}
+	return verses
+}
 
-	defer rows.Close()
-	
-	uniqueMap := make(map[int]struct{})
-	var uniqueChapters []int
-	
-	for rows.Next() {
-		var chapter int
-
-		if err := rows.Scan(&chapter); err != nil {
-			log.Fatal(err)
-		}
 
-		if _, exists := uniqueMap[chapter]; !exists {
-			uniqueMap [chapter] = struct{}{}
-			uniqueChapters = append(uniqueChapters, chapter)
-		}
+// This gives number of chapters in a book (0 if it doesn't exist). Use CountChapters to get the error instead.
+func GetAllChaptersInBook(db *sql.DB, book string) int {
+	chapters, err := CountChapters(db, book)
+	if err != nil {
+		PrintMessage("%s\n", err)
 	}
-
-	return len(uniqueChapters)
+	return chapters
 }
 
 
 // This Returns the width of the terminal (used for wordwrap)
 func termWidth() int {
-	termWidth, _, err := term.GetSize(0)
-	if err != nil {
-		fmt.Println("Error geting terminal width: ", err)
-		return 0
-  	}
+	termWidth, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || termWidth <= 0 {
+		// Not a terminal (ie piped into another program or a file), so just use the usual 80 wide
+		termWidth = 80
+	}
 
 	// Return with -1 so that it always has a gap of at least one spot on the right side. Just better readability.
 	return termWidth - 1
@@ -274,26 +247,47 @@ func termWidth() int {
 
 // Wraps the text so that it doesn't split a word in the middle
 func WordWrap(str string) {
-	lineWidth := termWidth()
+	fmt.Println(WrapString(str))
+}
+
+
+// Same as WordWrap, but gives back the wrapped text instead of printing it
+func WrapString(str string) string {
+	return strings.Join(WrapText(str, termWidth()), "\n")
+}
+
+
+// WrapText splits the text into lines that fit in width, without splitting a word in the middle.
+// WordWrap uses the whole terminal, this is for when the text has to fit in a column (ie side by side translations)
+func WrapText(str string, width int) []string {
 	words := strings.Fields(str)
 	if len(words) == 0 {
-		fmt.Println(str)
+		return []string{str}
 	}
 
-	wrapped := words[0]
-	spaceLeft := lineWidth - len(wrapped)
+	var lines []string
+	line := words[0]
+	spaceLeft := width - textWidth(line)
 
 	for _, word := range words[1:] {
-		if len(word)+1 > spaceLeft {
-			wrapped += "\n" + word
-			spaceLeft = lineWidth - len(word)
+		if textWidth(word)+1 > spaceLeft {
+			lines = append(lines, line)
+			line = word
+			spaceLeft = width - textWidth(word)
 		} else {
-			wrapped += " " + word
-			spaceLeft -= 1 + len(word)
+			line += " " + word
+			spaceLeft -= 1 + textWidth(word)
 		}
 	}
 
-	fmt.Println(wrapped)
+	return append(lines, line)
+}
+
+
+// How many spots on the screen the text takes up. len() counts bytes, which is wrong for anything that isn't plain ascii,
+// and the color codes don't take up any room at all
+func textWidth(str string) int {
+	return utf8.RuneCountInString(ansiCodes.ReplaceAllString(str, ""))
 }
 
 
@@ -302,8 +296,13 @@ func WordWrap(str string) {
 // -----------------------------------------------------------------------------
 
 type SaveData struct {
-	Bookmark  int   `json:"bookmark"`
+	Bookmark  int   `json:"bookmark"` // The default bookmark. Named ones are in Bookmarks
+	Bookmarks []NamedBookmark `json:"bookmarks,omitempty"`
 	Favorites []int `json:"favorites"`
+	FavoriteDetails []FavoriteDetail `json:"favoriteDetails,omitempty"` // Tags, notes and dates for the favorites
+	Notes     []Note `json:"notes,omitempty"`
+	Highlights []Highlight `json:"highlights,omitempty"`
+	HighlightColors map[string]string `json:"highlightColors,omitempty"` // Colors for categories that aren't built in
 }
 
 func (sd *SaveData) SetBookmark(id int) {
@@ -315,6 +314,7 @@ func (sd *SaveData) AddFavorite(item int) {
 	if !sd.ContainsFavorite(item) {
 		sd.Favorites = append(sd.Favorites, item)
 		sort.Ints(sd.Favorites) // Sort the slice after adding
+		sd.favoriteDetail(item).Added = time.Now()
 	}
 }
 
@@ -328,6 +328,14 @@ func (sd *SaveData) RemoveFavorite(item int) {
 			break // Exit the loop after removing the item
 		}
 	}
+
+	// The tags and note go with it
+	for i, detail := range sd.FavoriteDetails {
+		if detail.ID == item {
+			sd.FavoriteDetails = append(sd.FavoriteDetails[:i], sd.FavoriteDetails[i+1:]...)
+			break
+		}
+	}
 }
 
 
@@ -342,23 +350,40 @@ func (sd *SaveData) ContainsFavorite(item int) bool {
 }
 
 
-// Save function to save bookmarks and favorites to a file
-func (sd *SaveData) Save(filename string) error {
-	data, err := json.Marshal(sd)
+// Load function to load bookmarks and favorites from a json file. Everything is in user.db now (see userdata.go),
+// so this is only for importing the old bible-data.json
+func (sd *SaveData) Load(filename string) error {
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(filename, data, 0644)
+	return json.Unmarshal(data, sd)
 }
 
 
-// Load function to load bookmarks and favorites from a file
-func (sd *SaveData) Load(filename string) error {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
+// The saved data, for showing notes and highlights when verses are printed. It gets loaded the first time
+// a verse is printed, so printing a whole chapter doesn't read it again for every verse. UpdateUserData clears it.
+var cachedData *SaveData
+var cachedDataLock sync.Mutex
+
+func cachedSaveData() *SaveData {
+	cachedDataLock.Lock()
+	defer cachedDataLock.Unlock()
+	if cachedData == nil {
+		data, err := LoadSaveData()
+		if err != nil {
+			fmt.Println("Error loading data:", err)
+			data = &SaveData{}
+		}
+		cachedData = data
 	}
-	return json.Unmarshal(data, sd)
+	return cachedData
+}
+
+func clearCachedSaveData() {
+	cachedDataLock.Lock()
+	cachedData = nil
+	cachedDataLock.Unlock()
 }
 
 
@@ -372,118 +397,38 @@ func GetDataFilePath() string {
 
 // This will be a fovorites feature. Need to save to a file and be able to read it back (probably "bible -f" will list all favorites)
 func Favorites(db *sql.DB, id int) {
-
-	saveData := &SaveData{}
-
-	// Load existing data from file
-	dataFilePath := GetDataFilePath()
-	if err := saveData.Load(dataFilePath); err != nil && !os.IsNotExist(err) {
+	saveData, err := LoadSaveData()
+	if err != nil {
 		fmt.Println("Error loading data:", err)
+		return
 	}
 
 	if saveData.ContainsFavorite(id) {
 		verse := GetVerseFromId(db, id)
 		fmt.Printf("%s %d:%d already in favorites\n", verse.BookName, verse.Chapter, verse.Verse)
-		var choice string
-		fmt.Printf("Remove from favorites? (y or N) ")
-		fmt.Scanln(&choice)
+		choice := askLine("Remove from favorites? (y or N) ")
 		if choice == "y" {
-			saveData.RemoveFavorite(id)
-			fmt.Println("Verse WAS removed from favorites")
-
-			// Save data to file
-			if err := saveData.Save(dataFilePath); err != nil {
+			// Save it
+			err := UpdateUserData(func(ut *UserTx) error {
+				return ut.RemoveFavorite(id)
+			})
+			if err != nil {
 				fmt.Println("Error saving data:", err)
+				return
 			}
+			fmt.Println("Verse WAS removed from favorites")
 		} else {
 			fmt.Println("Verse WAS NOT removed from favorites")
 		}
 	} else {
-		saveData.AddFavorite(id)
-		fmt.Println("Added verse to favorites")
-
-		// Save data to file
-		if err := saveData.Save(dataFilePath); err != nil {
+		// Save it
+		err := UpdateUserData(func(ut *UserTx) error {
+			return ut.AddFavorite(id)
+		})
+		if err != nil {
 			fmt.Println("Error saving data:", err)
+			return
 		}
+		fmt.Println("Added verse to favorites")
 	}
 }
-
-
-// This lists your favorites. (ie. bible -f)
-func ListFavorites(db *sql.DB) {
-	saveData := &SaveData{}
-
-	// Load existing data from file
-	dataFilePath := GetDataFilePath()
-	if err := saveData.Load(dataFilePath); err != nil && !os.IsNotExist(err) {
-		fmt.Println("Error loading data:", err)
-	}
-
-	for _, id := range saveData.Favorites {
-		// Get info from id
-		verse := GetVerseFromId(db, id)
-
-		//Print Verse
-		PrintVerse(db, verse.BookName, strconv.Itoa(verse.Chapter), strconv.Itoa(verse.Verse))
-	}
-}
-
-
-// This will be a bookmark function in interactive mode
-func BookMark(id int) int{
-	var choice string
-	for {
-		fmt.Printf("Would you like to load or save bookmark? (l or s) ")
-		fmt.Scanln(&choice)
-
-		// Load the bookmark
-		if choice == "l" {
-			// LoadBookmark returns the id of the bookmark, so this just returns the id of the bookmark. Cyclical...
-			return LoadBookmark()
-		} else if choice == "s" {
-			saveData := &SaveData{}
-
-			// Load existing data from file
-			dataFilePath := GetDataFilePath()
-			if err := saveData.Load(dataFilePath); err != nil && !os.IsNotExist(err) {
-				fmt.Println("Error loading data:", err)
-			}
-
-			saveData.SetBookmark(id)
-
-			// Save data to file
-			if err := saveData.Save(dataFilePath); err != nil {
-				fmt.Println("Error saving data:", err)
-			}
-			
-			fmt.Println("Saved Bookmark!")
-
-			// Just keep the user on the same verse. 
-			return id
-		} else {
-			fmt.Println("Please select either l or s")
-		}
-	}
-	return -1 
-}
-
-
-// This is the load function for the very beginning of interactive mode. It needs to be seperate because I 
-// don't want to have to send anything with it. It merely returns the int of the bookmark variable. 
-func LoadBookmark() int {
-	saveData := &SaveData{}
-
-	// Load existing data from file
-	dataFilePath := GetDataFilePath()
-	if err := saveData.Load(dataFilePath); err != nil && !os.IsNotExist(err) {
-		fmt.Println("Error loading data:", err)
-	}
-
-	// If a bookmark hasn't been saved yet, just send the user to Genesis 1 1
-	if saveData.Bookmark == 0 {
-		return 1
-	}
-
-	return saveData.Bookmark
-}
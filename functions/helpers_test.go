@@ -0,0 +1,57 @@
+package functions
+
+import (
+	"fmt"
+	"testing"
+	"path/filepath"
+	"database/sql"
+)
+
+
+// The books in the test bible, with how many verses are in each chapter
+var testBooks = []struct {
+	name		string
+	book		int
+	chapters	[]int
+}{
+	{"Genesis", 1, []int{31, 25}},
+	{"John", 43, []int{51, 25, 36}},
+	{"Jude", 65, []int{25}},
+}
+
+
+// Makes a small bible database for tests. The ids go in order through it like the real one, so Genesis 1:1 is 1,
+// John 1:1 is 57 and Jude 1:1 is 169
+func newTestBibleDb(t *testing.T) *sql.DB {
+	t.Helper()
+	return writeTestBible(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+
+// Same as newTestBibleDb, but the file goes at path (ie in the translations folder)
+func writeTestBible(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { CloseDatabase(db) })
+
+	if _, err := db.Exec("CREATE TABLE bible (id INTEGER PRIMARY KEY, bookName TEXT, book INTEGER, chapter INTEGER, verse INTEGER, text TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	id := 1
+	for _, book := range testBooks {
+		for chapter, verses := range book.chapters {
+			for verse := 1; verse <= verses; verse++ {
+				text := fmt.Sprintf("%s %d:%d text", book.name, chapter+1, verse)
+				if _, err := db.Exec("INSERT INTO bible VALUES (?, ?, ?, ?, ?, ?)", id, book.name, book.book, chapter+1, verse, text); err != nil {
+					t.Fatal(err)
+				}
+				id++
+			}
+		}
+	}
+	return db
+}
@@ -0,0 +1,237 @@
+package functions
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"strings"
+	"database/sql"
+)
+
+
+// A highlighted verse. Every verse can be in one category
+type Highlight struct {
+	ID			int			`json:"id"`
+	Category	string		`json:"category"`
+	Created		time.Time	`json:"created"`
+}
+
+
+// The colors a category can be
+var highlightColors = map[string]string{
+	"red":		"\033[31m",
+	"green":	"\033[32m",
+	"yellow":	"\033[33m",
+	"blue":		"\033[34m",
+	"magenta":	"\033[35m",
+	"cyan":		"\033[36m",
+}
+
+
+// The categories that are always there, and their colors. Others can be made when highlighting
+var defaultCategories = map[string]string{
+	"promise":	"green",
+	"command":	"red",
+	"prophecy":	"magenta",
+}
+
+
+// FindHighlight gives back the index of the highlight on a verse, or -1 if it isn't highlighted
+func (sd *SaveData) FindHighlight(id int) int {
+	for i, h := range sd.Highlights {
+		if h.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+
+// SetHighlight highlights a verse in a category. If it was already highlighted, the category is changed
+func (ut *UserTx) SetHighlight(id int, category string) error {
+	_, err := ut.tx.Exec(`INSERT INTO highlights (verse_id, category, created) VALUES (?, ?, ?)
+		ON CONFLICT (verse_id) DO UPDATE SET category = excluded.category`,
+		id, category, formatTime(time.Now()))
+	return err
+}
+
+
+// RemoveHighlight takes the highlight off a verse
+func (ut *UserTx) RemoveHighlight(id int) error {
+	_, err := ut.tx.Exec("DELETE FROM highlights WHERE verse_id = ?", id)
+	return err
+}
+
+
+// SetCategoryColor sets the color for a category
+func (ut *UserTx) SetCategoryColor(category string, color string) error {
+	_, err := ut.tx.Exec("INSERT OR REPLACE INTO highlight_colors (category, color) VALUES (?, ?)", category, color)
+	return err
+}
+
+
+// CategoryColor gives back the color name for a category. Categories without one are yellow
+func (sd *SaveData) CategoryColor(category string) string {
+	if color, ok := sd.HighlightColors[category]; ok {
+		return color
+	}
+	if color, ok := defaultCategories[category]; ok {
+		return color
+	}
+	return "yellow"
+}
+
+
+// Categories gives back every category, the built in ones and any that have been made, sorted
+func (sd *SaveData) Categories() []string {
+	seen := make(map[string]bool)
+	for category := range defaultCategories {
+		seen[category] = true
+	}
+	for category := range sd.HighlightColors {
+		seen[category] = true
+	}
+	for _, h := range sd.Highlights {
+		seen[h.Category] = true
+	}
+
+	var categories []string
+	for category := range seen {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+
+// Highlights is the highlight version of Favorites. If the verse is highlighted it asks to remove it,
+// otherwise it asks which category to put it in
+func Highlights(db *sql.DB, id int) {
+	saveData, err := LoadSaveData()
+	if err != nil {
+		fmt.Println("Error loading data:", err)
+		return
+	}
+
+	verse := GetVerseFromId(db, id)
+
+	// The question gets asked first, then the change is saved. What to change is in here until then
+	var update func(ut *UserTx) error
+	var message string
+
+	if i := saveData.FindHighlight(id); i >= 0 {
+		fmt.Printf("%s %d:%d is highlighted as %s\n", verse.BookName, verse.Chapter, verse.Verse, saveData.Highlights[i].Category)
+		choice := askLine("Remove highlight? (y or N) ")
+		if choice != "y" {
+			fmt.Println("Highlight WAS NOT removed")
+			return
+		}
+		update = func(ut *UserTx) error {
+			return ut.RemoveHighlight(id)
+		}
+		message = "Highlight WAS removed"
+	} else {
+		category := strings.ToLower(askLine(fmt.Sprintf("Category? (%s, or a new one) ", strings.Join(saveData.Categories(), ", "))))
+		if category == "" {
+			fmt.Println("Not highlighted")
+			return
+		}
+
+		// A new category needs a color
+		color := ""
+		if !containsString(saveData.Categories(), category) {
+			color = strings.ToLower(askLine(fmt.Sprintf("Color for %s? (%s) ", category, strings.Join(colorNames(), ", "))))
+			if _, ok := highlightColors[color]; !ok {
+				color = "yellow"
+			}
+		}
+
+		update = func(ut *UserTx) error {
+			if color != "" {
+				if err := ut.SetCategoryColor(category, color); err != nil {
+					return err
+				}
+			}
+			return ut.SetHighlight(id, category)
+		}
+		message = "Highlighted as " + category
+	}
+
+	// Save it
+	err = UpdateUserData(update)
+	if err != nil {
+		fmt.Println("Error saving data:", err)
+		return
+	}
+
+	fmt.Println(message)
+}
+
+
+// ListHighlights prints the highlighted verses (bible highlights). If category isn't empty, only the ones in that category
+func ListHighlights(db *sql.DB, category string) {
+	saveData, err := LoadSaveData()
+	if err != nil {
+		fmt.Println("Error loading data:", err)
+		return
+	}
+
+	category = strings.ToLower(category)
+	found := false
+	for _, h := range saveData.Highlights {
+		if category != "" && h.Category != category {
+			continue
+		}
+		found = true
+		printBibleVerse(GetVerseFromId(db, h.ID))
+	}
+
+	if !found && IsTextOutput() {
+		if category != "" {
+			fmt.Printf("Nothing highlighted as %s. The categories are: %s\n", category, strings.Join(saveData.Categories(), ", "))
+		} else {
+			fmt.Println("Nothing highlighted yet. Use 'm' in interactive mode to highlight a verse")
+		}
+	}
+}
+
+
+// PrintVerseText prints the reference and the text of a verse, wrapped. If the verse is highlighted
+// the text is in the color of its category, and the category is after the reference
+func PrintVerseText(verse Bible) {
+	data := cachedSaveData()
+	i := data.FindHighlight(verse.ID)
+	if i < 0 {
+		fmt.Printf("%s %d:%d\n", verse.BookName, verse.Chapter, verse.Verse)
+		WordWrap(verse.Text)
+		return
+	}
+
+	category := data.Highlights[i].Category
+	color := highlightColors[data.CategoryColor(category)]
+	fmt.Printf("%s %d:%d %s\n", verse.BookName, verse.Chapter, verse.Verse, colorize("["+category+"]", color))
+	for _, line := range WrapText(verse.Text, termWidth()) {
+		fmt.Println(colorize(line, color))
+	}
+}
+
+
+// The names of the colors, sorted
+func colorNames() []string {
+	var names []string
+	for name := range highlightColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package functions
+
+import (
+	"reflect"
+	"testing"
+)
+
+
+func TestSetHighlight(t *testing.T) {
+	useTempUserDb(t)
+	data := updateAndLoad(t, func(ut *UserTx) error {
+		ut.SetHighlight(148, "promise")
+		ut.SetHighlight(57, "command")
+		return ut.SetHighlight(148, "prophecy")
+	})
+
+	if len(data.Highlights) != 2 || data.Highlights[0].ID != 57 || data.Highlights[1].Category != "prophecy" {
+		t.Errorf("highlights = %+v, want 57 as command and 148 as prophecy", data.Highlights)
+	}
+
+	data = updateAndLoad(t, func(ut *UserTx) error {
+		ut.RemoveHighlight(57)
+		return ut.RemoveHighlight(1)
+	})
+	if len(data.Highlights) != 1 || data.FindHighlight(148) != 0 {
+		t.Errorf("after removing 57 the highlights are %+v", data.Highlights)
+	}
+}
+
+
+func TestCategories(t *testing.T) {
+	data := SaveData{
+		Highlights: []Highlight{{ID: 1, Category: "creation"}},
+		HighlightColors: map[string]string{"grace": "blue", "promise": "cyan"},
+	}
+
+	if got, want := data.Categories(), []string{"command", "creation", "grace", "promise", "prophecy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Categories() = %v, want %v", got, want)
+	}
+
+	tests := map[string]string{
+		"grace": "blue",
+		// A saved color wins over the built in one
+		"promise": "cyan",
+		"command": "red",
+		"creation": "yellow",
+	}
+	for category, want := range tests {
+		if got := data.CategoryColor(category); got != want {
+			t.Errorf("CategoryColor(%q) = %q, want %q", category, got, want)
+		}
+	}
+}
+
+
+func TestHighlights(t *testing.T) {
+	useTempUserDb(t)
+	db := newTestBibleDb(t)
+
+	// A new category asks for a color too
+	useTestInput(t, "Grace\nblue\n")
+	captureOutput(t, func() { Highlights(db, 148) })
+	useTestInput(t, "promise\n")
+	captureOutput(t, func() { Highlights(db, 57) })
+
+	data, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Highlights) != 2 || data.Highlights[1].Category != "grace" || data.HighlightColors["grace"] != "blue" {
+		t.Fatalf("after highlighting the data is %+v", data)
+	}
+
+	// Highlighted verses say their category when they are printed
+	if out := captureOutput(t, func() { PrintVerseText(GetVerseFromId(db, 148)) }); out != "John 3:16 [grace]\nJohn 3:16 text\n" {
+		t.Errorf("PrintVerseText(John 3:16) printed %q", out)
+	}
+	if out := captureOutput(t, func() { ListHighlights(db, "PROMISE") }); out != "John 1:1 [promise]\nJohn 1:1 text\n\n" {
+		t.Errorf("ListHighlights(promise) printed %q", out)
+	}
+
+	// Asking again removes it, if you say yes
+	useTestInput(t, "n\n")
+	captureOutput(t, func() { Highlights(db, 148) })
+	useTestInput(t, "y\n")
+	captureOutput(t, func() { Highlights(db, 57) })
+	data, _ = LoadSaveData()
+	if len(data.Highlights) != 1 || data.Highlights[0].ID != 148 {
+		t.Errorf("after removing John 1:1 the highlights are %+v", data.Highlights)
+	}
+	if out := captureOutput(t, func() { PrintVerseText(GetVerseFromId(db, 57)) }); out != "John 1:1\nJohn 1:1 text\n" {
+		t.Errorf("PrintVerseText(John 1:1) printed %q", out)
+	}
+}
@@ -0,0 +1,199 @@
+package functions
+
+import (
+	"fmt"
+	"time"
+	"strings"
+	"strconv"
+	"database/sql"
+)
+
+
+// Everything that gets read is logged in the history table in user.db (see userMigrations), so "bible history"
+// can show what was read and when, and -i can carry on from the last verse.
+//
+// Times are saved in UTC as "2006-01-02 15:04:05" so they sort as text, and sqlite's date functions understand them
+const historyTimeFormat = "2006-01-02 15:04:05"
+
+
+// Reading the same thing again within this long doesn't get logged twice, and reading on from the
+// last thing within this long counts as the same sitting in "bible history"
+const historyGap = 30 * time.Minute
+
+
+// One thing that was read. Source is how it was read: reference (bible John 3), interactive (-i), reader (--tui) or random (-r)
+type HistoryEntry struct {
+	Start		int			`json:"start"`
+	End			int			`json:"end"`
+	Reference	string		`json:"reference"`
+	Viewed		time.Time	`json:"viewed"`
+	Source		string		`json:"source"`
+}
+
+
+// LogReading saves that the verses from start to end were just read. It doesn't say anything if it can't,
+// because not being able to save the history shouldn't get in the way of reading.
+// Scripts using --format json/csv/etc aren't reading, so those don't get logged.
+func LogReading(db *sql.DB, start int, end int, source string) {
+	if !IsTextOutput() || start <= 0 {
+		return
+	}
+
+	userDb, err := OpenUserDb()
+	if err != nil {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	// Don't log the same thing twice, ie the verse showing again after 'f' in interactive mode
+	var lastStart, lastEnd int
+	var lastViewed string
+	err = userDb.QueryRow("SELECT start_id, end_id, viewed FROM history ORDER BY id DESC LIMIT 1").Scan(&lastStart, &lastEnd, &lastViewed)
+	if err == nil && lastStart == start && lastEnd == end {
+		if viewed, err := time.ParseInLocation(historyTimeFormat, lastViewed, time.UTC); err == nil && now.Sub(viewed) < historyGap {
+			return
+		}
+	}
+
+	userDb.Exec("INSERT INTO history (start_id, end_id, reference, viewed, source) VALUES (?, ?, ?, ?, ?)",
+		start, end, IdRangeString(db, start, end), now.Format(historyTimeFormat), source)
+}
+
+
+// LastReading gives back the first verse of the last thing that was read, so -i can carry on from there
+func LastReading() (HistoryEntry, bool) {
+	entries, err := LoadHistory(time.Time{}, time.Time{}, 1)
+	if err != nil || len(entries) == 0 {
+		return HistoryEntry{}, false
+	}
+	return entries[0], true
+}
+
+
+// LoadHistory gives back what was read from since up to (not including) until, newest first.
+// A zero time means no limit on that end, and so does a limit of 0
+func LoadHistory(since time.Time, until time.Time, limit int) ([]HistoryEntry, error) {
+	userDb, err := OpenUserDb()
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT start_id, end_id, reference, viewed, source FROM history WHERE 1 = 1"
+	var args []any
+	if !since.IsZero() {
+		query += " AND viewed >= ?"
+		args = append(args, since.UTC().Format(historyTimeFormat))
+	}
+	if !until.IsZero() {
+		query += " AND viewed < ?"
+		args = append(args, until.UTC().Format(historyTimeFormat))
+	}
+	query += " ORDER BY id DESC"
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+
+	rows, err := userDb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var viewed string
+		if err := rows.Scan(&entry.Start, &entry.End, &entry.Reference, &viewed, &entry.Source); err != nil {
+			return nil, err
+		}
+		entry.Viewed, _ = time.ParseInLocation(historyTimeFormat, viewed, time.UTC)
+		entry.Viewed = entry.Viewed.Local()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+
+// GroupHistory joins up reading that carries straight on, ie going through John 3 one verse at a time in -i
+// is one "John 3:1-36" instead of 36 lines. The entries are newest first, and so are the groups
+func GroupHistory(db *sql.DB, entries []HistoryEntry) []HistoryEntry {
+	var groups []HistoryEntry
+	// A group's time is when it started, this is when the newest thing in it was read
+	var lastViewed time.Time
+
+	// Going from oldest to newest, so "carries on" means the next one starts where the last one ended
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if n := len(groups); n > 0 {
+			last := &groups[n-1]
+			if entry.Source == last.Source && entry.Start >= last.Start && entry.Start <= last.End+1 && entry.Viewed.Sub(lastViewed) < historyGap {
+				last.End = max(last.End, entry.End)
+				lastViewed = entry.Viewed
+				continue
+			}
+		}
+		groups = append(groups, entry)
+		lastViewed = entry.Viewed
+	}
+
+	// Newest first again, with the references for the joined up ranges
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+	for i := range groups {
+		groups[i].Reference = IdRangeString(db, groups[i].Start, groups[i].End)
+	}
+	return groups
+}
+
+
+// ParseHistoryDate understands the dates for "bible history --since", ie 2026-10-01, today, yesterday or 7d (7 days ago).
+// It gives back the start of that day
+func ParseHistoryDate(date string) (time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	date = strings.ToLower(strings.TrimSpace(date))
+	switch date {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	// A number of days ago, ie 7d
+	if strings.HasSuffix(date, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(date, "d")); err == nil {
+			return today.AddDate(0, 0, -days), nil
+		}
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Can't understand the date \"%s\", use something like 2026-10-01, today, yesterday or 7d", date)
+	}
+	return day, nil
+}
+
+
+// PrintHistory prints the history, oldest at the top so the newest is right above the prompt, under a heading for each day
+func PrintHistory(entries []HistoryEntry) {
+	if len(entries) == 0 {
+		fmt.Println("Nothing read yet (or nothing in those dates)")
+		return
+	}
+
+	lastDay := ""
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if day := entry.Viewed.Format("Monday January 2, 2006"); day != lastDay {
+			if lastDay != "" {
+				fmt.Println()
+			}
+			fmt.Println(day)
+			lastDay = day
+		}
+		fmt.Printf("  %s  %-30s %s\n", entry.Viewed.Format("15:04"), entry.Reference, colorize("("+entry.Source+")", colorNote))
+	}
+}
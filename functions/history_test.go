@@ -0,0 +1,143 @@
+package functions
+
+import (
+	"testing"
+	"time"
+)
+
+
+func TestGroupHistory(t *testing.T) {
+	db := newTestBibleDb(t)
+	at := func(minutes int) time.Time {
+		return time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC).Add(time.Duration(minutes) * time.Minute)
+	}
+
+	// Newest first, like LoadHistory gives them. John 1 is 57-107, John 2 is 108-132 and John 3 is 133-168
+	entries := []HistoryEntry{
+		// Back to Genesis the next day, on its own
+		{Start: 1, End: 31, Viewed: at(24 * 60), Source: "reader"},
+		// A random verse doesn't join the reading, even though it's in John 3
+		{Start: 140, End: 140, Viewed: at(85), Source: "random"},
+		// Jude comes right after John 3, but too long after it
+		{Start: 169, End: 193, Viewed: at(80), Source: "reader"},
+		// Reading on through John
+		{Start: 133, End: 168, Viewed: at(20), Source: "reader"},
+		{Start: 108, End: 132, Viewed: at(10), Source: "reader"},
+		{Start: 57, End: 107, Viewed: at(0), Source: "reader"},
+	}
+
+	want := []HistoryEntry{
+		{Start: 1, End: 31, Reference: "Genesis 1:1-31", Viewed: at(24 * 60), Source: "reader"},
+		{Start: 140, End: 140, Reference: "John 3:8", Viewed: at(85), Source: "random"},
+		{Start: 169, End: 193, Reference: "Jude 1:1-25", Viewed: at(80), Source: "reader"},
+		{Start: 57, End: 168, Reference: "John 1:1-3:36", Viewed: at(0), Source: "reader"},
+	}
+
+	got := GroupHistory(db, entries)
+	if len(got) != len(want) {
+		t.Fatalf("GroupHistory() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GroupHistory() group %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+
+func TestGroupHistoryGoingBack(t *testing.T) {
+	db := newTestBibleDb(t)
+	at := func(minutes int) time.Time {
+		return time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC).Add(time.Duration(minutes) * time.Minute)
+	}
+
+	// Reading a verse that was already in the last range still counts as carrying on, but going back before it doesn't
+	entries := []HistoryEntry{
+		{Start: 57, End: 57, Viewed: at(10), Source: "interactive"},
+		{Start: 110, End: 110, Viewed: at(5), Source: "interactive"},
+		{Start: 108, End: 132, Viewed: at(0), Source: "interactive"},
+	}
+
+	got := GroupHistory(db, entries)
+	if len(got) != 2 || got[0].Reference != "John 1:1" || got[1].Reference != "John 2:1-25" || !got[1].Viewed.Equal(at(0)) {
+		t.Errorf("GroupHistory() = %+v, want John 1:1 then John 2:1-25", got)
+	}
+}
+
+
+func TestParseHistoryDate(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		date	string
+		want	time.Time
+	}{
+		{"today", today},
+		{" Today ", today},
+		{"yesterday", today.AddDate(0, 0, -1)},
+		{"0d", today},
+		{"7d", today.AddDate(0, 0, -7)},
+		{"2026-10-01", time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, test := range tests {
+		got, err := ParseHistoryDate(test.date)
+		if err != nil {
+			t.Errorf("ParseHistoryDate(%q) gave an error: %v", test.date, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("ParseHistoryDate(%q) = %s, want %s", test.date, got, test.want)
+		}
+	}
+
+	for _, date := range []string{"", "tomorrow", "d", "xd", "2026-13-01", "1/10/2026"} {
+		if got, err := ParseHistoryDate(date); err == nil {
+			t.Errorf("ParseHistoryDate(%q) = %s, want an error", date, got)
+		}
+	}
+}
+
+
+func TestLogReading(t *testing.T) {
+	useTempUserDb(t)
+	db := newTestBibleDb(t)
+
+	if _, ok := LastReading(); ok {
+		t.Errorf("LastReading() found something before anything was read")
+	}
+
+	LogReading(db, 148, 150, "reference")
+	// The same thing again straight away only counts once
+	LogReading(db, 148, 150, "interactive")
+	LogReading(db, 57, 107, "reader")
+	// Nothing to log
+	LogReading(db, 0, 0, "random")
+
+	// Scripts aren't reading
+	useOutputFormat(t, "json")
+	LogReading(db, 1, 31, "reference")
+	outputFormat = "text"
+
+	entries, err := LoadHistory(time.Time{}, time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Reference != "John 1:1-51" || entries[1].Reference != "John 3:16-18" || entries[1].Source != "reference" {
+		t.Fatalf("LoadHistory() = %+v, want John 1 then John 3:16-18", entries)
+	}
+
+	if last, ok := LastReading(); !ok || last.Start != 57 {
+		t.Errorf("LastReading() = %+v, %v, want John 1:1", last, ok)
+	}
+	if entries, _ := LoadHistory(time.Time{}, time.Time{}, 1); len(entries) != 1 {
+		t.Errorf("LoadHistory() with a limit of 1 gave back %d", len(entries))
+	}
+	if entries, _ := LoadHistory(time.Now().Add(time.Hour), time.Time{}, 0); len(entries) != 0 {
+		t.Errorf("LoadHistory() since an hour from now gave back %+v", entries)
+	}
+	if entries, _ := LoadHistory(time.Time{}, time.Now().Add(-time.Hour), 0); len(entries) != 0 {
+		t.Errorf("LoadHistory() until an hour ago gave back %+v", entries)
+	}
+}
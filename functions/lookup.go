@@ -0,0 +1,295 @@
+package functions
+
+import (
+	"fmt"
+	"sync"
+	"errors"
+	"math/rand"
+	"database/sql"
+)
+
+
+// ErrNotFound is what the lookups give back when a book, chapter or verse doesn't exist.
+// Check for it with errors.Is(err, ErrNotFound), the message itself still says what couldn't be found.
+var ErrNotFound = errors.New("not found")
+
+
+// An error for something that doesn't exist. It counts as ErrNotFound for errors.Is
+type notFoundError struct {
+	message	string
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+func (e *notFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func notFound(format string, a ...any) error {
+	return &notFoundError{message: fmt.Sprintf(format, a...)}
+}
+
+
+// A book, with how many chapters it has
+type BookInfo struct {
+	Name		string	`json:"name"`
+	Book		int		`json:"book"`
+	Chapters	int		`json:"chapters"`
+}
+
+
+// A chapter and how many verses it has
+type ChapterInfo struct {
+	Chapter	int	`json:"chapter"`
+	Verses	int	`json:"verses"`
+}
+
+
+// OpenDatabase opens a bible database file, and makes sure it actually has the bible table in it
+func OpenDatabase(path string) (*sql.DB, error) {
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// sql.Open doesn't actually check anything, so do a quick query to make sure it's usable
+	var id int
+	if err := db.QueryRow("SELECT id FROM bible LIMIT 1").Scan(&id); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s doesn't look like a bible database: %v", path, err)
+	}
+
+	return db, nil
+}
+
+
+// LookupVerse gives back one verse, or an error if it doesn't exist. The book can be an abbreviation.
+func LookupVerse(db *sql.DB, book string, chapter int, verse int) (Bible, error) {
+	var bibleVerse Bible
+	query := "SELECT id, bookName, book, chapter, verse, text FROM bible WHERE bookName = ? AND chapter = ? AND verse = ?"
+	err := db.QueryRow(query, bookName(book), chapter, verse).Scan(&bibleVerse.ID, &bibleVerse.BookName, &bibleVerse.Book, &bibleVerse.Chapter, &bibleVerse.Verse, &bibleVerse.Text)
+	if err == sql.ErrNoRows {
+		return bibleVerse, notFound("Can't find %s %d:%d", book, chapter, verse)
+	}
+	return bibleVerse, err
+}
+
+
+// LookupVerseId is the same as GetVerseFromId, but gives back the error instead of printing it
+func LookupVerseId(db *sql.DB, id int) (Bible, error) {
+	var verse Bible
+	query := "SELECT id, bookName, book, chapter, verse, text FROM bible where id = ?"
+	err := db.QueryRow(query, id).Scan(&verse.ID, &verse.BookName, &verse.Book, &verse.Chapter, &verse.Verse, &verse.Text)
+	if err == sql.ErrNoRows {
+		return verse, notFound("Can't find a verse with id %d", id)
+	}
+	return verse, err
+}
+
+
+// LookupPassage gives back every verse in a reference, ie "John 3:16-18; Romans 8:28"
+func LookupPassage(db *sql.DB, reference string) ([]Bible, error) {
+	ref, err := ParseReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	verses := []Bible{}
+	for _, r := range ref.Ranges {
+		rangeVerses, err := GetVersesInRange(db, r)
+		if err != nil {
+			return nil, err
+		}
+		verses = append(verses, rangeVerses...)
+	}
+
+	return verses, nil
+}
+
+
+// LookupRandomVerse picks a random book, then a random chapter in it, then a random verse in that
+func LookupRandomVerse(db *sql.DB) (Bible, error) {
+	book := allBooks[rand.Intn(len(allBooks))]
+
+	chapters, err := CountChapters(db, book)
+	if err != nil {
+		return Bible{}, err
+	}
+	chapter := rand.Intn(chapters) + 1
+
+	verses, err := CountVerses(db, book, chapter)
+	if err != nil {
+		return Bible{}, err
+	}
+
+	return LookupVerse(db, book, chapter, rand.Intn(verses) + 1)
+}
+
+
+// How many verses are in each chapter of each book, and the ids of the first and last ones, for each database that has been
+// opened. There's no index on the bible table, so counting one chapter means going through the whole thing. Things like
+// "bible stats" need every chapter, so the first time any are needed they all get counted in one go. The databases never
+// change, so it never needs to be redone. CloseDatabase forgets them.
+var verseCounts = make(map[*sql.DB]map[string]map[int]chapterCount)
+var verseCountsLock sync.Mutex
+
+type chapterCount struct {
+	verses	int
+	first	int
+	last	int
+}
+
+
+// Gives back the verse counts for db (book name -> chapter -> verses and ids), counting them first if they haven't been yet
+func chapterVerseCounts(db *sql.DB) (map[string]map[int]chapterCount, error) {
+	verseCountsLock.Lock()
+	defer verseCountsLock.Unlock()
+
+	if counts, ok := verseCounts[db]; ok {
+		return counts, nil
+	}
+
+	rows, err := db.Query("SELECT bookName, chapter, COUNT(*), MIN(id), MAX(id) FROM bible GROUP BY bookName, chapter")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]map[int]chapterCount)
+	for rows.Next() {
+		var book string
+		var chapter int
+		var count chapterCount
+		if err := rows.Scan(&book, &chapter, &count.verses, &count.first, &count.last); err != nil {
+			return nil, err
+		}
+		if counts[book] == nil {
+			counts[book] = make(map[int]chapterCount)
+		}
+		counts[book][chapter] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	verseCounts[db] = counts
+	return counts, nil
+}
+
+
+// CloseDatabase closes a bible database and forgets its verse counts. Use this instead of db.Close() for the bible
+// databases, otherwise a program that keeps opening and closing them (ie with the client package) keeps every one around
+func CloseDatabase(db *sql.DB) error {
+	verseCountsLock.Lock()
+	delete(verseCounts, db)
+	verseCountsLock.Unlock()
+	return db.Close()
+}
+
+
+// CountChapters gives the number of chapters in a book
+func CountChapters(db *sql.DB, book string) (int, error) {
+	counts, err := chapterVerseCounts(db)
+	if err != nil {
+		return 0, err
+	}
+	chapters := len(counts[bookName(book)])
+	if chapters == 0 {
+		return 0, notFound("Can't find book \"%s\"", book)
+	}
+	return chapters, nil
+}
+
+
+// CountVerses gives the number of verses in a chapter
+func CountVerses(db *sql.DB, book string, chapter int) (int, error) {
+	counts, err := chapterVerseCounts(db)
+	if err != nil {
+		return 0, err
+	}
+	verses := counts[bookName(book)][chapter].verses
+	if verses == 0 {
+		return 0, notFound("Can't find chapter %d in book \"%s\"", chapter, book)
+	}
+	return verses, nil
+}
+
+
+// Gives back the ids of the first and last verse in a chapter. Same as GetIdRange for a whole chapter, but from the counts
+func chapterIds(db *sql.DB, book string, chapter int) (int, int, error) {
+	counts, err := chapterVerseCounts(db)
+	if err != nil {
+		return 0, 0, err
+	}
+	count, ok := counts[bookName(book)][chapter]
+	if !ok {
+		return 0, 0, notFound("Can't find chapter %d in book \"%s\"", chapter, book)
+	}
+	return count.first, count.last, nil
+}
+
+
+// ListBooks gives back every book in the translation, in order, with how many chapters it has
+func ListBooks(db *sql.DB) ([]BookInfo, error) {
+	rows, err := db.Query("SELECT bookName, book, COUNT(DISTINCT chapter) FROM bible GROUP BY book ORDER BY book")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []BookInfo{}
+	for rows.Next() {
+		var book BookInfo
+		if err := rows.Scan(&book.Name, &book.Book, &book.Chapters); err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+
+	return books, rows.Err()
+}
+
+
+// ListChapters gives back every chapter in a book, with how many verses it has
+func ListChapters(db *sql.DB, book string) ([]ChapterInfo, error) {
+	rows, err := db.Query("SELECT chapter, COUNT(*) FROM bible WHERE bookName = ? GROUP BY chapter ORDER BY chapter", bookName(book))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chapters []ChapterInfo
+	for rows.Next() {
+		var chapter ChapterInfo
+		if err := rows.Scan(&chapter.Chapter, &chapter.Verses); err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, chapter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(chapters) == 0 {
+		return nil, notFound("Can't find book \"%s\"", book)
+	}
+	return chapters, nil
+}
+
+
+// IdRangeString gives back the reference for a range of verse ids, ie "John 3:16-18"
+func IdRangeString(db *sql.DB, start int, end int) string {
+	first, err := LookupVerseId(db, start)
+	if err != nil {
+		return fmt.Sprintf("verse %d", start)
+	}
+	last, err := LookupVerseId(db, end)
+	if err != nil {
+		last = first
+	}
+
+	r := VerseRange{Book: first.BookName, Chapter: first.Chapter, Verse: first.Verse, EndBook: last.BookName, EndChapter: last.Chapter, EndVerse: last.Verse}
+	return r.String()
+}
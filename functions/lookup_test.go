@@ -0,0 +1,24 @@
+package functions
+
+import (
+	"testing"
+)
+
+
+func TestCloseDatabaseForgetsCounts(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		db := newTestBibleDb(t)
+		if _, err := CountVerses(db, "John", 3); err != nil {
+			t.Fatal(err)
+		}
+		if err := CloseDatabase(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	verseCountsLock.Lock()
+	defer verseCountsLock.Unlock()
+	if len(verseCounts) != 0 {
+		t.Errorf("the counts for %d closed databases are still there", len(verseCounts))
+	}
+}
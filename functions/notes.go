@@ -0,0 +1,214 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"strings"
+	"os/exec"
+	"database/sql"
+)
+
+
+// A note on a verse or a range of verses. Start and End are verse ids, they are the same if it's only one verse.
+// The text is markdown, and can be as many lines as you want.
+type Note struct {
+	Start		int			`json:"start"`
+	End			int			`json:"end"`
+	Reference	string		`json:"reference"`
+	Text		string		`json:"text"`
+	Created		time.Time	`json:"created"`
+	Updated		time.Time	`json:"updated"`
+}
+
+
+// A dim cyan for notes, so they don't look like part of the verse
+const colorNote = "\033[36m"
+
+
+// FindNote gives back the index of the note on exactly that range, or -1 if there isn't one
+func (sd *SaveData) FindNote(start int, end int) int {
+	for i, note := range sd.Notes {
+		if note.Start == start && note.End == end {
+			return i
+		}
+	}
+	return -1
+}
+
+
+// SetNote adds a note, or replaces the text of the one that is already on the same range
+func (ut *UserTx) SetNote(note Note) error {
+	_, err := ut.tx.Exec(`INSERT INTO notes (start_id, end_id, reference, text, created, updated) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (start_id, end_id) DO UPDATE SET reference = excluded.reference, text = excluded.text, updated = excluded.updated`,
+		note.Start, note.End, note.Reference, note.Text, formatTime(note.Created), formatTime(note.Updated))
+	return err
+}
+
+
+// RemoveNote removes the note on a range. It gives back false if there wasn't one
+func (ut *UserTx) RemoveNote(start int, end int) (bool, error) {
+	return ut.execChanged("DELETE FROM notes WHERE start_id = ? AND end_id = ?", start, end)
+}
+
+
+// SearchNotes gives back the notes that have every word of the search in them (the text or the reference). Case doesn't matter
+func (sd *SaveData) SearchNotes(search string) []Note {
+	words := strings.Fields(strings.ToLower(search))
+	var found []Note
+	for _, note := range sd.Notes {
+		text := strings.ToLower(note.Reference + " " + note.Text)
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			found = append(found, note)
+		}
+	}
+	return found
+}
+
+
+// EditNote opens the note on a range in $EDITOR, and saves it when the editor is closed.
+// If there isn't a note yet it starts a new one. If everything is deleted out of it, the note is removed.
+func EditNote(db *sql.DB, start int, end int) {
+	saveData, err := LoadSaveData()
+	if err != nil {
+		fmt.Println("Error loading data:", err)
+		return
+	}
+
+	reference := IdRangeString(db, start, end)
+	existing := ""
+	if i := saveData.FindNote(start, end); i >= 0 {
+		existing = saveData.Notes[i].Text
+	}
+
+	text, err := OpenEditor(existing)
+	if err != nil {
+		fmt.Println("Error editing note:", err)
+		return
+	}
+	text = strings.TrimSpace(text)
+
+	if text == existing {
+		fmt.Println("Note not changed")
+		return
+	}
+
+	// Save it
+	err = UpdateUserData(func(ut *UserTx) error {
+		if text == "" {
+			_, err := ut.RemoveNote(start, end)
+			return err
+		}
+		now := time.Now()
+		return ut.SetNote(Note{Start: start, End: end, Reference: reference, Text: text, Created: now, Updated: now})
+	})
+	if err != nil {
+		fmt.Println("Error saving data:", err)
+		return
+	}
+
+	if text == "" {
+		fmt.Printf("Removed note on %s\n", reference)
+	} else {
+		fmt.Printf("Saved note on %s\n", reference)
+	}
+}
+
+
+// OpenEditor opens some text in the user's editor ($VISUAL or $EDITOR, or vi if neither is set) and gives back what it was changed to
+func OpenEditor(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	tmpFile, err := os.CreateTemp("", "bible-note-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(text); err != nil {
+		tmpFile.Close()
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", err
+	}
+
+	// The editor can have arguments in it, ie EDITOR="code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], tmpFile.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %v", editor, err)
+	}
+
+	data, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+
+// PrintVerseNotes prints the notes that start at a verse, under it. This is how notes show up when reading
+func PrintVerseNotes(id int) {
+	for _, note := range cachedSaveData().Notes {
+		if note.Start == id {
+			PrintNote(note)
+		}
+	}
+}
+
+
+// PrintNote prints a note, indented with a bar down the side so it stands out from the verses
+func PrintNote(note Note) {
+	fmt.Println(colorize("  Note on "+note.Reference+":", colorNote))
+	for _, line := range strings.Split(note.Text, "\n") {
+		for _, wrapped := range WrapText(line, termWidth()-4) {
+			fmt.Println(colorize("  | ", colorNote) + wrapped)
+		}
+	}
+	fmt.Println()
+}
+
+
+// ListNotes prints all the notes, or only the ones that match a search (bible notes --search)
+func ListNotes(search string) {
+	saveData, err := LoadSaveData()
+	if err != nil {
+		fmt.Println("Error loading data:", err)
+		return
+	}
+
+	notes := saveData.Notes
+	if search != "" {
+		notes = saveData.SearchNotes(search)
+	}
+
+	if len(notes) == 0 {
+		if search != "" {
+			fmt.Println("No notes found matching: ", search)
+		} else {
+			fmt.Println("No notes yet. Add one with 'a' in interactive mode, or bible notes add John 3:16")
+		}
+		return
+	}
+
+	for _, note := range notes {
+		PrintNote(note)
+	}
+}
@@ -0,0 +1,154 @@
+package functions
+
+import (
+	"os"
+	"time"
+	"reflect"
+	"testing"
+	"path/filepath"
+)
+
+
+// Sets EDITOR to a script that puts text in the file it's given
+func useTestEditor(t *testing.T, text string) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "editor")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf '%s' '"+text+"' > \"$1\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script)
+}
+
+
+func noteStarts(notes []Note) []int {
+	var starts []int
+	for _, note := range notes {
+		starts = append(starts, note.Start)
+	}
+	return starts
+}
+
+
+func TestSetNote(t *testing.T) {
+	useTempUserDb(t)
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := updateAndLoad(t, func(ut *UserTx) error {
+		ut.SetNote(Note{Start: 148, End: 150, Text: "first", Created: created})
+		ut.SetNote(Note{Start: 57, End: 57, Text: "In the beginning"})
+		return ut.SetNote(Note{Start: 148, End: 148, Text: "just the one verse"})
+	})
+
+	// Sorted by where they start
+	if starts := noteStarts(data.Notes); !reflect.DeepEqual(starts, []int{57, 148, 148}) {
+		t.Errorf("notes start at %v, want 57, 148, 148", starts)
+	}
+
+	// Same range replaces it, but keeps when it was made
+	data = updateAndLoad(t, func(ut *UserTx) error {
+		return ut.SetNote(Note{Start: 148, End: 150, Text: "changed", Created: time.Now()})
+	})
+	if i := data.FindNote(148, 150); i < 0 || data.Notes[i].Text != "changed" || !data.Notes[i].Created.Equal(created) {
+		t.Errorf("after changing it the note is %+v", data.Notes)
+	}
+	if len(data.Notes) != 3 {
+		t.Errorf("changing a note added another one: %+v", data.Notes)
+	}
+
+	var removed, removedMissing bool
+	data = updateAndLoad(t, func(ut *UserTx) error {
+		removed, _ = ut.RemoveNote(148, 148)
+		removedMissing, _ = ut.RemoveNote(1, 1)
+		return nil
+	})
+	if !removed || data.FindNote(148, 148) >= 0 {
+		t.Errorf("RemoveNote(148, 148) didn't remove it")
+	}
+	if removedMissing {
+		t.Errorf("RemoveNote(1, 1) removed a note that isn't there")
+	}
+}
+
+
+func TestSearchNotes(t *testing.T) {
+	data := SaveData{Notes: []Note{
+		{Start: 57, Reference: "John 1:1", Text: "The Word is **Jesus**"},
+		{Start: 148, Reference: "John 3:16", Text: "God's love for the world"},
+		{Start: 169, Reference: "Jude 1:1", Text: "Written to the called"},
+	}}
+
+	tests := []struct {
+		search	string
+		want	[]int
+	}{
+		{"jesus", []int{57}},
+		{"john", []int{57, 148}},
+		{"the world", []int{148}},
+		{"LOVE jude", nil},
+		{"", []int{57, 148, 169}},
+	}
+
+	for _, test := range tests {
+		if got := noteStarts(data.SearchNotes(test.search)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SearchNotes(%q) = %v, want %v", test.search, got, test.want)
+		}
+	}
+}
+
+
+func TestEditNote(t *testing.T) {
+	useTempUserDb(t)
+	db := newTestBibleDb(t)
+
+	useTestEditor(t, "A note\nwith two lines\n")
+	captureOutput(t, func() { EditNote(db, 148, 150) })
+
+	data, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Notes) != 1 || data.Notes[0].Reference != "John 3:16-18" || data.Notes[0].Text != "A note\nwith two lines" {
+		t.Fatalf("after adding a note the notes are %+v", data.Notes)
+	}
+
+	// It gets printed under the verse it starts at
+	if out := captureOutput(t, func() { PrintVerseNotes(148) }); out != "  Note on John 3:16-18:\n  | A note\n  | with two lines\n\n" {
+		t.Errorf("PrintVerseNotes(148) printed %q", out)
+	}
+	if out := captureOutput(t, func() { PrintVerseNotes(149) }); out != "" {
+		t.Errorf("PrintVerseNotes(149) printed %q, the note starts at 148", out)
+	}
+
+	// Deleting everything removes the note
+	useTestEditor(t, "")
+	captureOutput(t, func() { EditNote(db, 148, 150) })
+	data, _ = LoadSaveData()
+	if len(data.Notes) != 0 {
+		t.Errorf("after emptying the note the notes are %+v", data.Notes)
+	}
+	if out := captureOutput(t, func() { PrintVerseNotes(148) }); out != "" {
+		t.Errorf("a removed note is still printed: %q", out)
+	}
+}
+
+
+func TestIdRangeString(t *testing.T) {
+	db := newTestBibleDb(t)
+	tests := []struct {
+		start	int
+		end		int
+		want	string
+	}{
+		{148, 148, "John 3:16"},
+		{148, 150, "John 3:16-18"},
+		{131, 134, "John 2:24-3:2"},
+		{168, 170, "John 3:36 - Jude 1:2"},
+		{500, 500, "verse 500"},
+	}
+
+	for _, test := range tests {
+		if got := IdRangeString(db, test.start, test.end); got != test.want {
+			t.Errorf("IdRangeString(%d, %d) = %q, want %q", test.start, test.end, got, test.want)
+		}
+	}
+}
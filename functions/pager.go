@@ -0,0 +1,67 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"strings"
+	"golang.org/x/term"
+)
+
+
+// Page prints the text, and if it's taller than the terminal it shows it one screen at a time (like more).
+// If the output isn't a terminal it just prints it all.
+func Page(text string) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	// Some terminals say they are 0 high, so don't bother paging with those either
+	if err != nil || height < 2 || !term.IsTerminal(int(os.Stdin.Fd())) || len(lines) < height {
+		fmt.Println(strings.Join(lines, "\n"))
+		return
+	}
+
+	// Leave the bottom line for the prompt
+	pageSize := height - 1
+	shown := 0
+	show := func(count int) {
+		for ; count > 0 && shown < len(lines); count-- {
+			fmt.Println(lines[shown])
+			shown++
+		}
+	}
+
+	show(pageSize)
+	for shown < len(lines) {
+		fmt.Printf("-- More (%d%%) -- space: next page, enter: next line, q: quit", shown*100/len(lines))
+		key := readKey()
+		// Clear the prompt so the next lines go where it was
+		fmt.Print("\r\033[K")
+
+		switch key {
+		case "q", "Q", "\x03", "\x1b":
+			return
+		case "\r", "\n", "j", "\x1b[B":
+			show(1)
+		default:
+			show(pageSize)
+		}
+	}
+}
+
+
+// Reads a single key press without needing enter. Arrow keys come through as their escape code, ie "\x1b[B" for down
+func readKey() string {
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return "q"
+	}
+	defer term.Restore(fd, oldState)
+
+	buf := make([]byte, 8)
+	n, err := os.Stdin.Read(buf)
+	if err != nil || n == 0 {
+		return "q"
+	}
+	return string(buf[:n])
+}
@@ -0,0 +1,497 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"strings"
+	"path/filepath"
+	"database/sql"
+	"encoding/json"
+)
+
+
+// A reading plan. Every day is a reference with what to read that day, ie "Genesis 1-3; Matthew 1"
+type ReadingPlan struct {
+	Name		string		`json:"name"`
+	Description	string		`json:"description"`
+	Days		[]string	`json:"days"`
+	Builtin		bool		`json:"-"`
+}
+
+
+// The built in plans. They are worked out from the chapters in the database, split up so every day is about the same length
+var builtinPlans = []struct {
+	name		string
+	description	string
+	days		int
+	tracks		[][]string	// Books read side by side, ie M'Cheyne reads from four places every day
+}{
+	{"year", "The whole bible in a year, in order", 365, [][]string{allBooks}},
+	{"chronological", "The whole bible in a year, with the books in roughly the order things happened", 365, [][]string{chronologicalBooks}},
+	{"nt90", "The new testament in 90 days", 90, [][]string{allBooks[39:]}},
+	{"mcheyne", "M'Cheyne's plan, four readings a day starting at Genesis, Matthew, Ezra and Acts", 365, [][]string{
+		allBooks[:14],	// Genesis - 2 Chronicles
+		allBooks[39:],	// Matthew - Revelation
+		allBooks[14:39],	// Ezra - Malachi
+		append(append([]string{}, allBooks[43:]...), allBooks[39:43]...),	// Acts - Revelation, then the gospels
+	}},
+}
+
+
+// The books in roughly the order they happened. It's only by book, so the chapters in a book stay in order
+var chronologicalBooks = []string{
+	"Genesis", "Job", "Exodus", "Leviticus", "Numbers", "Deuteronomy",
+	"Joshua", "Judges", "Ruth", "1 Samuel", "2 Samuel", "1 Chronicles",
+	"Psalms", "Proverbs", "Ecclesiastes", "Song of Solomon", "1 Kings", "2 Kings",
+	"2 Chronicles", "Jonah", "Amos", "Hosea", "Joel", "Isaiah",
+	"Micah", "Nahum", "Zephaniah", "Habakkuk", "Jeremiah", "Lamentations",
+	"Obadiah", "Ezekiel", "Daniel", "Ezra", "Haggai", "Zechariah",
+	"Esther", "Nehemiah", "Malachi",
+	"Matthew", "Mark", "Luke", "John", "Acts", "James",
+	"Galatians", "1 Thessalonians", "2 Thessalonians", "1 Corinthians", "2 Corinthians", "Romans",
+	"Ephesians", "Philippians", "Colossians", "Philemon", "1 Timothy", "Titus",
+	"1 Peter", "2 Timothy", "2 Peter", "Hebrews", "Jude", "1 John",
+	"2 John", "3 John", "Revelation",
+}
+
+
+// How far through a plan you are. It's saved in user.db (see userMigrations), it used to be in plan-progress.json
+type PlanProgress struct {
+	Plan	string		`json:"plan"`
+	Start	string		`json:"start"`	// The day the plan was started, ie 2024-01-01
+	Done	[]PlanDay	`json:"done"`
+}
+
+
+// A day of a plan that has been read. Ranges are the verse ids that were read (first and last id of each reading),
+// so it still means something even if the plan file gets changed
+type PlanDay struct {
+	Day		int			`json:"day"`
+	Date	string		`json:"date"`
+	Ranges	[][2]int	`json:"ranges"`
+}
+
+
+// The format of the dates for plans, ie when one was started
+const planDateFormat = "2006-01-02"
+
+
+// GetPlansDir gives back the folder for custom reading plans (~/.local/share/bible/plans)
+func GetPlansDir() string {
+	return filepath.Join(filepath.Dir(GetDataFilePath()), "plans")
+}
+
+
+// GetPlanProgressPath gives back where the reading plan progress used to be saved, before it was in user.db
+func GetPlanProgressPath() string {
+	return filepath.Join(filepath.Dir(GetDataFilePath()), "plan-progress.json")
+}
+
+
+// ReadingPlans gives back all the plans. The built in ones first, then the custom ones from the plans folder.
+func ReadingPlans(db *sql.DB) ([]ReadingPlan, error) {
+	chapters, err := listAllChapters(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var plans []ReadingPlan
+	for _, builtin := range builtinPlans {
+		plans = append(plans, ReadingPlan{
+			Name: builtin.name,
+			Description: builtin.description,
+			Days: buildPlanDays(chapters, builtin.tracks, builtin.days),
+			Builtin: true,
+		})
+	}
+
+	files, _ := filepath.Glob(filepath.Join(GetPlansDir(), "*"))
+	sort.Strings(files)
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		plan, err := LoadPlanFile(file)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+
+// FindReadingPlan looks up a plan by name
+func FindReadingPlan(db *sql.DB, name string) (ReadingPlan, error) {
+	plans, err := ReadingPlans(db)
+	if err != nil {
+		return ReadingPlan{}, err
+	}
+
+	var names []string
+	for _, plan := range plans {
+		if strings.EqualFold(plan.Name, name) {
+			return plan, nil
+		}
+		names = append(names, plan.Name)
+	}
+
+	return ReadingPlan{}, notFound("Can't find reading plan \"%s\". The plans are: %s", name, strings.Join(names, ", "))
+}
+
+
+// LoadPlanFile reads a custom plan from a json or yaml file. The name is the file name if the file doesn't have one.
+//
+// json:
+//
+//	{"name": "gospels", "description": "The gospels in a month", "days": ["Matthew 1-3", "Matthew 4-6; Psalm 1"]}
+//
+// yaml:
+//
+//	name: gospels
+//	description: The gospels in a month
+//	days:
+//	  - Matthew 1-3
+//	  - Matthew 4-6; Psalm 1
+func LoadPlanFile(path string) (ReadingPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ReadingPlan{}, err
+	}
+
+	var plan ReadingPlan
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &plan)
+	} else {
+		plan, err = parsePlanYaml(string(data))
+	}
+	if err != nil {
+		return ReadingPlan{}, fmt.Errorf("Error reading plan %s: %v", path, err)
+	}
+
+	if plan.Name == "" {
+		plan.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(plan.Days) == 0 {
+		return ReadingPlan{}, fmt.Errorf("Plan %s doesn't have any days in it", path)
+	}
+
+	// Check every day now, so a typo shows up when the plan is loaded and not months later
+	for i, day := range plan.Days {
+		if strings.TrimSpace(day) == "" {
+			continue
+		}
+		if _, err := ParseReference(day); err != nil {
+			return ReadingPlan{}, fmt.Errorf("Plan %s, day %d: %v", path, i+1, err)
+		}
+	}
+
+	return plan, nil
+}
+
+
+// Reads the small bit of yaml that a plan needs: name, description and a list of days.
+// It isn't a real yaml parser, but it doesn't need to be for this.
+func parsePlanYaml(data string) (ReadingPlan, error) {
+	var plan ReadingPlan
+	inDays := false
+
+	for i, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if !inDays {
+				return plan, fmt.Errorf("line %d: a list item that isn't under days:", i+1)
+			}
+			plan.Days = append(plan.Days, yamlString(strings.TrimPrefix(trimmed, "-")))
+			continue
+		}
+
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found {
+			return plan, fmt.Errorf("line %d: expected \"key: value\"", i+1)
+		}
+
+		inDays = false
+		switch strings.TrimSpace(key) {
+		case "name":
+			plan.Name = yamlString(value)
+		case "description":
+			plan.Description = yamlString(value)
+		case "days":
+			inDays = true
+		default:
+			return plan, fmt.Errorf("line %d: unknown key \"%s\"", i+1, strings.TrimSpace(key))
+		}
+	}
+
+	return plan, nil
+}
+
+
+// Takes the quotes off a yaml value, if it has them
+func yamlString(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1:len(value)-1]
+	}
+	return value
+}
+
+
+// A chapter in a book, and how many verses it has
+type planChapter struct {
+	book	string
+	chapter	int
+	verses	int
+}
+
+
+// Every chapter in the bible, by book
+func listAllChapters(db *sql.DB) (map[string][]planChapter, error) {
+	rows, err := db.Query("SELECT bookName, chapter, COUNT(*) FROM bible GROUP BY book, chapter ORDER BY book, chapter")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chapters := make(map[string][]planChapter)
+	for rows.Next() {
+		var c planChapter
+		if err := rows.Scan(&c.book, &c.chapter, &c.verses); err != nil {
+			return nil, err
+		}
+		chapters[c.book] = append(chapters[c.book], c)
+	}
+
+	return chapters, rows.Err()
+}
+
+
+// Splits the books in each track over the days. A chapter goes on the day that its middle falls on,
+// so the days come out about the same number of verses. Every track is split on its own, then they get joined.
+func buildPlanDays(chapters map[string][]planChapter, tracks [][]string, days int) []string {
+	readings := make([][]string, days)
+
+	for _, track := range tracks {
+		var trackChapters []planChapter
+		total := 0
+		for _, book := range track {
+			for _, c := range chapters[book] {
+				trackChapters = append(trackChapters, c)
+				total += c.verses
+			}
+		}
+
+		dayChapters := make([][]planChapter, days)
+		sofar := 0
+		for _, c := range trackChapters {
+			day := (sofar*2 + c.verses) * days / (total * 2)
+			dayChapters[day] = append(dayChapters[day], c)
+			sofar += c.verses
+		}
+
+		for day, list := range dayChapters {
+			if len(list) > 0 {
+				readings[day] = append(readings[day], chapterListString(list))
+			}
+		}
+	}
+
+	planDays := make([]string, days)
+	for day, list := range readings {
+		planDays[day] = strings.Join(list, "; ")
+	}
+	return planDays
+}
+
+
+// Turns a list of chapters into a reference, ie "Genesis 49-50; Exodus 1"
+func chapterListString(list []planChapter) string {
+	var parts []string
+	for i := 0; i < len(list); {
+		j := i
+		for j+1 < len(list) && list[j+1].book == list[i].book && list[j+1].chapter == list[j].chapter+1 {
+			j++
+		}
+
+		switch {
+		case isSingleChapterBook(list[i].book):
+			parts = append(parts, list[i].book)
+		case i == j:
+			parts = append(parts, fmt.Sprintf("%s %d", list[i].book, list[i].chapter))
+		default:
+			parts = append(parts, fmt.Sprintf("%s %d-%d", list[i].book, list[i].chapter, list[j].chapter))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, "; ")
+}
+
+
+// Load reads the plan progress. If there isn't any yet, it's just empty
+func (p *PlanProgress) Load() error {
+	userDb, err := OpenUserDb()
+	if err != nil {
+		return err
+	}
+
+	*p = PlanProgress{}
+	err = userDb.QueryRow("SELECT name, start FROM reading_plan").Scan(&p.Plan, &p.Start)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := userDb.Query(`SELECT plan_progress.day, date, start_id, end_id FROM plan_progress
+		LEFT JOIN plan_progress_ranges ON plan_progress_ranges.day = plan_progress.day
+		ORDER BY plan_progress.day, start_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day PlanDay
+		var start, end sql.NullInt64
+		if err := rows.Scan(&day.Day, &day.Date, &start, &end); err != nil {
+			return err
+		}
+		if n := len(p.Done); n == 0 || p.Done[n-1].Day != day.Day {
+			day.Ranges = [][2]int{}
+			p.Done = append(p.Done, day)
+		}
+		// A day with nothing to read in it doesn't have any ranges
+		if start.Valid {
+			last := &p.Done[len(p.Done)-1]
+			last.Ranges = append(last.Ranges, [2]int{int(start.Int64), int(end.Int64)})
+		}
+	}
+	return rows.Err()
+}
+
+
+// StartPlan starts a plan, replacing the one before it and everything that was read in it
+func (ut *UserTx) StartPlan(plan string, start string) error {
+	for _, query := range []string{"DELETE FROM plan_progress_ranges", "DELETE FROM plan_progress"} {
+		if _, err := ut.tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	_, err := ut.tx.Exec("INSERT OR REPLACE INTO reading_plan (id, name, start) VALUES (1, ?, ?)", plan, start)
+	return err
+}
+
+
+// MarkPlanDay saves that a day of the plan has been read. If it already was, nothing changes
+func (ut *UserTx) MarkPlanDay(day PlanDay) error {
+	added, err := ut.execChanged("INSERT OR IGNORE INTO plan_progress (day, date) VALUES (?, ?)", day.Day, day.Date)
+	if err != nil || !added {
+		return err
+	}
+	for _, r := range day.Ranges {
+		if _, err := ut.tx.Exec("INSERT INTO plan_progress_ranges (day, start_id, end_id) VALUES (?, ?, ?)", day.Day, r[0], r[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+
+// DayNumber gives back which day of the plan it is, starting at 1 on the day it was started
+func (p *PlanProgress) DayNumber(now time.Time) (int, error) {
+	start, err := time.ParseInLocation(planDateFormat, p.Start, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("Bad start date \"%s\" in %s", p.Start, GetUserDbPath())
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	// Round it, so a day that is 23 or 25 hours long (daylight savings) still counts as one day
+	return int(math.Round(today.Sub(start).Hours() / 24)) + 1, nil
+}
+
+
+// IsDone is true if that day has been read
+func (p *PlanProgress) IsDone(day int) bool {
+	for _, done := range p.Done {
+		if done.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+
+// MarkDone marks a day as read, and saves it with the verse ids that it covered
+func (p *PlanProgress) MarkDone(db *sql.DB, plan ReadingPlan, day int, now time.Time) error {
+	if day < 1 || day > len(plan.Days) {
+		return fmt.Errorf("Day %d isn't in the plan, it has %d days", day, len(plan.Days))
+	}
+	if p.IsDone(day) {
+		return nil
+	}
+
+	ranges, err := ReadingIdRanges(db, plan.Days[day-1])
+	if err != nil {
+		return err
+	}
+
+	done := PlanDay{Day: day, Date: now.Format(planDateFormat), Ranges: ranges}
+	err = UpdateUserData(func(ut *UserTx) error {
+		return ut.MarkPlanDay(done)
+	})
+	if err != nil {
+		return err
+	}
+
+	p.Done = append(p.Done, done)
+	sort.Slice(p.Done, func(i, j int) bool { return p.Done[i].Day < p.Done[j].Day })
+	return nil
+}
+
+
+// ReadingIdRanges gives back the first and last verse id of every part of a reading
+func ReadingIdRanges(db *sql.DB, reading string) ([][2]int, error) {
+	ranges := [][2]int{}
+	if strings.TrimSpace(reading) == "" {
+		return ranges, nil
+	}
+
+	ref, err := ParseReference(reading)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range ref.Ranges {
+		start, end, err := GetIdRange(db, r)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges, nil
+}
+
+
+// VersesRead gives back how many different verses have been read in the plan so far
+func (p *PlanProgress) VersesRead() int {
+	read := make(map[int]bool)
+	for _, day := range p.Done {
+		for _, r := range day.Ranges {
+			for id := r[0]; id <= r[1]; id++ {
+				read[id] = true
+			}
+		}
+	}
+	return len(read)
+}
@@ -0,0 +1,207 @@
+package functions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+
+// Some chapters that are all the same length, so it's easy to work out where the days should split
+func testChapters(book string, count int, verses int) []planChapter {
+	var chapters []planChapter
+	for i := 1; i <= count; i++ {
+		chapters = append(chapters, planChapter{book: book, chapter: i, verses: verses})
+	}
+	return chapters
+}
+
+
+func TestBuildPlanDays(t *testing.T) {
+	chapters := map[string][]planChapter{
+		"Genesis":	testChapters("Genesis", 4, 10),
+		"Exodus":	testChapters("Exodus", 2, 10),
+		"Matthew":	testChapters("Matthew", 3, 20),
+		"Jude":		testChapters("Jude", 1, 10),
+	}
+
+	tests := []struct {
+		name	string
+		tracks	[][]string
+		days	int
+		want	[]string
+	}{
+		{"one track", [][]string{{"Genesis", "Exodus"}}, 3, []string{"Genesis 1-2", "Genesis 3-4", "Exodus 1-2"}},
+		{"into the next book", [][]string{{"Genesis", "Exodus"}}, 2, []string{"Genesis 1-3", "Genesis 4; Exodus 1-2"}},
+		{"one a day", [][]string{{"Exodus", "Jude"}}, 3, []string{"Exodus 1", "Exodus 2", "Jude"}},
+		// Each track is split on its own, then the days get joined
+		{"two tracks", [][]string{{"Genesis"}, {"Matthew"}}, 2, []string{"Genesis 1-2; Matthew 1", "Genesis 3-4; Matthew 2-3"}},
+		// More days than chapters leaves some days empty
+		{"empty days", [][]string{{"Exodus"}}, 4, []string{"", "Exodus 1", "", "Exodus 2"}},
+	}
+
+	for _, test := range tests {
+		got := buildPlanDays(chapters, test.tracks, test.days)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: buildPlanDays() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+
+func TestBuildPlanDaysCoversEverything(t *testing.T) {
+	chapters := map[string][]planChapter{"Psalms": testChapters("Psalms", 150, 7)}
+	days := buildPlanDays(chapters, [][]string{{"Psalms"}}, 30)
+
+	// Every chapter once, in order, with no day getting much more than its share
+	next := 1
+	for i, day := range days {
+		ref, err := ParseReference(day)
+		if err != nil {
+			t.Fatalf("Day %d %q doesn't parse: %v", i+1, day, err)
+		}
+		r := ref.Ranges[0]
+		if r.Chapter != next {
+			t.Fatalf("Day %d starts at Psalms %d, want %d", i+1, r.Chapter, next)
+		}
+		if chapters := r.EndChapter - r.Chapter + 1; chapters > 6 {
+			t.Errorf("Day %d has %d chapters, want about 5", i+1, chapters)
+		}
+		next = r.EndChapter + 1
+	}
+	if next != 151 {
+		t.Errorf("The plan ends at Psalms %d, want 150", next-1)
+	}
+}
+
+
+func TestDayNumber(t *testing.T) {
+	tests := []struct {
+		start	string
+		now		time.Time
+		want	int
+	}{
+		{"2026-01-01", time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local), 1},
+		{"2026-01-01", time.Date(2026, 1, 1, 23, 59, 0, 0, time.Local), 1},
+		{"2026-01-01", time.Date(2026, 1, 2, 0, 1, 0, 0, time.Local), 2},
+		{"2026-01-01", time.Date(2026, 12, 31, 12, 0, 0, 0, time.Local), 365},
+		// Over the daylight savings changes (in places that have them)
+		{"2026-03-01", time.Date(2026, 4, 1, 8, 0, 0, 0, time.Local), 32},
+		{"2026-10-01", time.Date(2026, 11, 15, 8, 0, 0, 0, time.Local), 46},
+		// Before the plan starts
+		{"2026-01-10", time.Date(2026, 1, 9, 12, 0, 0, 0, time.Local), 0},
+	}
+
+	for _, test := range tests {
+		progress := PlanProgress{Plan: "test", Start: test.start}
+		got, err := progress.DayNumber(test.now)
+		if err != nil {
+			t.Errorf("DayNumber(%s) with start %s gave an error: %v", test.now, test.start, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("DayNumber(%s) with start %s = %d, want %d", test.now, test.start, got, test.want)
+		}
+	}
+
+	progress := PlanProgress{Plan: "test", Start: "someday"}
+	if _, err := progress.DayNumber(time.Now()); err == nil {
+		t.Errorf("DayNumber() with a bad start date should give an error")
+	}
+}
+
+
+func TestParsePlanYaml(t *testing.T) {
+	data := `# A plan for a week
+name: "gospels-week"
+description: 'The start of each gospel'
+
+days:
+  - Matthew 1-2
+  - Mark 1
+  -
+  - "Luke 1; Luke 2:1-20"
+  - John 1
+`
+	plan, err := parsePlanYaml(data)
+	if err != nil {
+		t.Fatalf("parsePlanYaml() gave an error: %v", err)
+	}
+
+	want := ReadingPlan{
+		Name: "gospels-week",
+		Description: "The start of each gospel",
+		Days: []string{"Matthew 1-2", "Mark 1", "", "Luke 1; Luke 2:1-20", "John 1"},
+	}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("parsePlanYaml() = %+v, want %+v", plan, want)
+	}
+}
+
+
+func TestParsePlanYamlErrors(t *testing.T) {
+	tests := []struct {
+		data	string
+		message	string
+	}{
+		{"- John 1", "line 1: a list item that isn't under days"},
+		{"name: test\nwhat is this", "line 2: expected \"key: value\""},
+		{"name: test\nauthor: me", "line 2: unknown key \"author\""},
+		// The days are over once another key starts
+		{"days:\n  - John 1\nname: test\n  - John 2", "line 4: a list item that isn't under days"},
+	}
+
+	for _, test := range tests {
+		_, err := parsePlanYaml(test.data)
+		if err == nil || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("parsePlanYaml(%q) error = %v, want %q", test.data, err, test.message)
+		}
+	}
+}
+
+
+func TestMarkDone(t *testing.T) {
+	useTempUserDb(t)
+	db := newTestBibleDb(t)
+	plan := ReadingPlan{Name: "test", Days: []string{"Genesis 1", "John 3:16; Jude"}}
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.Local)
+
+	err := UpdateUserData(func(ut *UserTx) error {
+		return ut.StartPlan("test", "2026-10-17")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := &PlanProgress{}
+	if err := progress.MarkDone(db, plan, 2, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := progress.MarkDone(db, plan, 3, now); err == nil {
+		t.Errorf("MarkDone() with a day that isn't in the plan should give an error")
+	}
+
+	// It's saved, so the next time it's loaded it's still there
+	saved := &PlanProgress{}
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	want := []PlanDay{{Day: 2, Date: "2026-10-18", Ranges: [][2]int{{148, 148}, {169, 193}}}}
+	if saved.Plan != "test" || !reflect.DeepEqual(saved.Done, want) {
+		t.Errorf("after MarkDone() the plan progress is %+v, want %+v", saved, want)
+	}
+	if saved.VersesRead() != 26 {
+		t.Errorf("VersesRead() = %d, want 26", saved.VersesRead())
+	}
+
+	// Starting again clears it
+	err = UpdateUserData(func(ut *UserTx) error {
+		return ut.StartPlan("other", "2026-10-18")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saved.Load(); err != nil || saved.Plan != "other" || len(saved.Done) != 0 {
+		t.Errorf("after starting another plan the progress is %+v, %v", saved, err)
+	}
+}
@@ -0,0 +1,450 @@
+package functions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+
+// A search gets parsed into a tree of these. ie `love AND (mercy OR grace) NOT hate` or `faith NEAR/5 hope`
+// Each one knows how to turn itself into fts5 syntax, or into a plain SQL LIKE check for when there is no search index.
+type SearchExpr interface {
+	fts() string
+	sql(text string) (string, []any)
+	terms() []wordExpr
+}
+
+
+// A single word. If prefix is true it matches anything that starts with it (ie bless* matches blessed)
+type wordExpr struct {
+	word	string
+	prefix	bool
+}
+
+// "A quoted phrase", the words have to be right next to each other
+type phraseExpr struct {
+	words	[]string
+}
+
+// Both sides have to match
+type andExpr struct {
+	left, right	SearchExpr
+}
+
+// Either side can match
+type orExpr struct {
+	left, right	SearchExpr
+}
+
+// Matches if left does and right doesn't, ie `love NOT hate`
+type notExpr struct {
+	left, right	SearchExpr
+}
+
+// All the words/phrases have to be within distance words of each other, ie `faith NEAR/5 hope`
+type nearExpr struct {
+	items		[]SearchExpr
+	distance	int
+}
+
+
+// How close NEAR is if there isn't a /number. This is what fts5 uses too.
+const defaultNearDistance = 10
+
+
+func (w wordExpr) fts() string {
+	if w.prefix {
+		return quoteFts(w.word) + "*"
+	}
+	return quoteFts(w.word)
+}
+
+func (p phraseExpr) fts() string {
+	return quoteFts(strings.Join(p.words, " "))
+}
+
+func (a andExpr) fts() string {
+	return "(" + a.left.fts() + " AND " + a.right.fts() + ")"
+}
+
+func (o orExpr) fts() string {
+	return "(" + o.left.fts() + " OR " + o.right.fts() + ")"
+}
+
+func (n notExpr) fts() string {
+	return "(" + n.left.fts() + " NOT " + n.right.fts() + ")"
+}
+
+func (n nearExpr) fts() string {
+	var items []string
+	for _, item := range n.items {
+		items = append(items, item.fts())
+	}
+	return fmt.Sprintf("NEAR(%s, %d)", strings.Join(items, " "), n.distance)
+}
+
+
+// The words that a match would have in it, so they can be highlighted. Anything after a NOT is left out.
+func (w wordExpr) terms() []wordExpr {
+	return []wordExpr{w}
+}
+
+func (p phraseExpr) terms() []wordExpr {
+	var words []wordExpr
+	for _, word := range p.words {
+		words = append(words, wordExpr{word: word})
+	}
+	return words
+}
+
+func (a andExpr) terms() []wordExpr {
+	return append(a.left.terms(), a.right.terms()...)
+}
+
+func (o orExpr) terms() []wordExpr {
+	return append(o.left.terms(), o.right.terms()...)
+}
+
+func (n notExpr) terms() []wordExpr {
+	return n.left.terms()
+}
+
+func (n nearExpr) terms() []wordExpr {
+	var words []wordExpr
+	for _, item := range n.items {
+		words = append(words, item.terms()...)
+	}
+	return words
+}
+
+
+// Whether there is a NEAR anywhere in the search. Without the search index it can't be done properly
+func hasNear(expr SearchExpr) bool {
+	switch e := expr.(type) {
+	case nearExpr:
+		return true
+	case andExpr:
+		return hasNear(e.left) || hasNear(e.right)
+	case orExpr:
+		return hasNear(e.left) || hasNear(e.right)
+	case notExpr:
+		return hasNear(e.left) || hasNear(e.right)
+	}
+	return false
+}
+
+
+// The LIKE versions. text is the SQL for the verse text with the punctuation taken out and a space on each end,
+// so "% love %" is a whole word and "% love%" is the start of a word.
+func (w wordExpr) sql(text string) (string, []any) {
+	if w.prefix {
+		return text + likeSql, []any{"% " + escapeLike(w.word) + "%"}
+	}
+	return text + likeSql, []any{"% " + escapeLike(w.word) + " %"}
+}
+
+func (p phraseExpr) sql(text string) (string, []any) {
+	return text + likeSql, []any{"% " + escapeLike(strings.Join(p.words, " ")) + " %"}
+}
+
+func (a andExpr) sql(text string) (string, []any) {
+	return joinSql(text, a.left, "AND", a.right)
+}
+
+func (o orExpr) sql(text string) (string, []any) {
+	return joinSql(text, o.left, "OR", o.right)
+}
+
+func (n notExpr) sql(text string) (string, []any) {
+	return joinSql(text, n.left, "AND NOT", n.right)
+}
+
+// LIKE can't check how far apart words are, so without the search index NEAR just means they are all in the verse
+func (n nearExpr) sql(text string) (string, []any) {
+	var parts []string
+	var params []any
+	for _, item := range n.items {
+		part, p := item.sql(text)
+		parts = append(parts, part)
+		params = append(params, p...)
+	}
+	return "(" + strings.Join(parts, " AND ") + ")", params
+}
+
+// % and _ are wildcards in LIKE, so they get a \ in front of them to only match themselves (ie a search for 100%)
+const likeSql = " LIKE ? ESCAPE '\\'"
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+func joinSql(text string, left SearchExpr, op string, right SearchExpr) (string, []any) {
+	l, lp := left.sql(text)
+	r, rp := right.sql(text)
+	return "(" + l + " " + op + " " + r + ")", append(lp, rp...)
+}
+
+
+// ParseSearch parses a search into a tree. It understands:
+//   love mercy          both words (AND is implied)
+//   love AND mercy      same thing
+//   love OR mercy       either word
+//   love NOT hate       love, but not if hate is there too
+//   "so loved"          a phrase
+//   bless*              anything starting with bless
+//   faith NEAR/5 hope   within 5 words of each other
+//   (love OR mercy) AND God
+// The operators have to be in capitals, so "and" is still just a word. If exact is false every word is a prefix.
+func ParseSearch(search string, exact bool) (SearchExpr, error) {
+	tokens, err := tokenizeSearch(search)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("Nothing to search for")
+	}
+
+	p := &searchParser{tokens: tokens, exact: exact}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected \"%s\" in search", p.tokens[p.pos].text)
+	}
+
+	return expr, nil
+}
+
+
+// A piece of the search. phrase is true if it was in quotes (so "AND" in quotes is just a word)
+type searchToken struct {
+	text	string
+	phrase	bool
+}
+
+
+// Splits the search into words, "phrases", and brackets
+func tokenizeSearch(search string) ([]searchToken, error) {
+	var tokens []searchToken
+	var word strings.Builder
+
+	endWord := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, searchToken{text: word.String()})
+			word.Reset()
+		}
+	}
+
+	runes := []rune(search)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"':
+			endWord()
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("Missing a closing \" in search")
+			}
+			phrase := strings.Join(strings.Fields(string(runes[i+1:end])), " ")
+			if phrase != "" {
+				tokens = append(tokens, searchToken{text: phrase, phrase: true})
+			}
+			i = end
+		case r == '(' || r == ')':
+			endWord()
+			tokens = append(tokens, searchToken{text: string(r)})
+		case r == ' ' || r == '\t' || r == '\n':
+			endWord()
+		default:
+			word.WriteRune(r)
+		}
+	}
+	endWord()
+
+	return tokens, nil
+}
+
+
+type searchParser struct {
+	tokens	[]searchToken
+	pos		int
+	exact	bool
+}
+
+
+// Looks at the next token without using it up. Gives back "" at the end
+func (p *searchParser) peek() string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].phrase {
+		if p.pos < len(p.tokens) {
+			return "\"" + p.tokens[p.pos].text + "\""
+		}
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+
+// OR is the loosest, so it's at the top
+func (p *searchParser) parseOr() (SearchExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "OR" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+
+	return left, nil
+}
+
+
+// AND, NOT, or nothing at all between two things (which means AND)
+func (p *searchParser) parseAnd() (SearchExpr, error) {
+	left, err := p.parseNear()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		next := p.peek()
+		switch {
+		case next == "AND":
+			p.pos++
+			right, err := p.parseNear()
+			if err != nil {
+				return nil, err
+			}
+			left = andExpr{left, right}
+		case next == "NOT":
+			p.pos++
+			right, err := p.parseNear()
+			if err != nil {
+				return nil, err
+			}
+			left = notExpr{left, right}
+		case next == "" || next == "OR" || next == ")":
+			return left, nil
+		default:
+			right, err := p.parseNear()
+			if err != nil {
+				return nil, err
+			}
+			left = andExpr{left, right}
+		}
+	}
+}
+
+
+// NEAR sticks the tightest. It only works between words and phrases, ie `faith NEAR/5 hope NEAR/5 charity`
+func (p *searchParser) parseNear() (SearchExpr, error) {
+	first, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	near := nearExpr{items: []SearchExpr{first}, distance: -1}
+	for isNearToken(p.peek()) {
+		distance, err := parseNearDistance(p.peek())
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+
+		next, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		if !isNearItem(near.items[len(near.items)-1]) || !isNearItem(next) {
+			return nil, fmt.Errorf("NEAR only works between words and \"phrases\"")
+		}
+		near.items = append(near.items, next)
+		// If there are a few NEARs in a row, the biggest distance wins. fts5 only has one distance per NEAR group
+		near.distance = max(near.distance, distance)
+	}
+
+	if len(near.items) == 1 {
+		return first, nil
+	}
+	return near, nil
+}
+
+
+// Works out the distance from "NEAR/5". Just "NEAR" is the default distance
+func parseNearDistance(token string) (int, error) {
+	if token == "NEAR" {
+		return defaultNearDistance, nil
+	}
+
+	distance, err := strconv.Atoi(strings.TrimPrefix(token, "NEAR/"))
+	if err != nil || !strings.HasPrefix(token, "NEAR/") || distance < 0 {
+		return 0, fmt.Errorf("Invalid \"%s\", expected something like NEAR/5", token)
+	}
+	return distance, nil
+}
+
+
+func isNearToken(token string) bool {
+	return token == "NEAR" || strings.HasPrefix(token, "NEAR/")
+}
+
+
+func isNearItem(expr SearchExpr) bool {
+	switch expr.(type) {
+	case wordExpr, phraseExpr:
+		return true
+	}
+	return false
+}
+
+
+// A word, a "phrase", or something in brackets
+func (p *searchParser) parsePrimary() (SearchExpr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("Search ends too early, something is missing after the last word")
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+
+	if token.phrase {
+		words := strings.Fields(token.text)
+		if len(words) == 1 {
+			return wordExpr{word: words[0]}, nil
+		}
+		return phraseExpr{words: words}, nil
+	}
+
+	switch {
+	case token.text == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("Missing a closing ) in search")
+		}
+		p.pos++
+		return expr, nil
+	case token.text == ")" || token.text == "AND" || token.text == "OR" || token.text == "NOT" || isNearToken(token.text):
+		return nil, fmt.Errorf("Unexpected \"%s\" in search", token.text)
+	}
+
+	// A plain word. Words ending in * are always prefixes, and everything is if it's not an exact search
+	word := strings.TrimRight(token.text, "*")
+	if word == "" {
+		return nil, fmt.Errorf("A * needs some letters in front of it, ie bless*")
+	}
+	return wordExpr{word: word, prefix: !p.exact || strings.HasSuffix(token.text, "*")}, nil
+}
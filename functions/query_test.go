@@ -0,0 +1,154 @@
+package functions
+
+import (
+	"reflect"
+	"testing"
+)
+
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		search	string
+		exact	bool
+		fts		string
+		sql		string
+		params	[]any
+	}{
+		{"love", false, `"love"*`, "t LIKE ? ESCAPE '\\'", []any{"% love%"}},
+		{"love", true, `"love"`, "t LIKE ? ESCAPE '\\'", []any{"% love %"}},
+		{"love mercy", false, `("love"* AND "mercy"*)`, "(t LIKE ? ESCAPE '\\' AND t LIKE ? ESCAPE '\\')", []any{"% love%", "% mercy%"}},
+		{"love AND mercy", true, `("love" AND "mercy")`, "(t LIKE ? ESCAPE '\\' AND t LIKE ? ESCAPE '\\')", []any{"% love %", "% mercy %"}},
+		// NOT sticks tighter than OR
+		{"love OR mercy NOT hate", true, `("love" OR ("mercy" NOT "hate"))`, "(t LIKE ? ESCAPE '\\' OR (t LIKE ? ESCAPE '\\' AND NOT t LIKE ? ESCAPE '\\'))", []any{"% love %", "% mercy %", "% hate %"}},
+		{"(love OR mercy) AND God", true, `(("love" OR "mercy") AND "God")`, "((t LIKE ? ESCAPE '\\' OR t LIKE ? ESCAPE '\\') AND t LIKE ? ESCAPE '\\')", []any{"% love %", "% mercy %", "% God %"}},
+		{`"so loved"`, false, `"so loved"`, "t LIKE ? ESCAPE '\\'", []any{"% so loved %"}},
+		// A * is always a prefix, even for an exact search
+		{"bless*", true, `"bless"*`, "t LIKE ? ESCAPE '\\'", []any{"% bless%"}},
+		{"faith NEAR/5 hope", true, `NEAR("faith" "hope", 5)`, "(t LIKE ? ESCAPE '\\' AND t LIKE ? ESCAPE '\\')", []any{"% faith %", "% hope %"}},
+		{"faith NEAR hope", true, `NEAR("faith" "hope", 10)`, "(t LIKE ? ESCAPE '\\' AND t LIKE ? ESCAPE '\\')", []any{"% faith %", "% hope %"}},
+		// The biggest distance wins when there are a few NEARs in a row
+		{"faith NEAR/2 hope NEAR/5 charity", true, `NEAR("faith" "hope" "charity", 5)`, "(t LIKE ? ESCAPE '\\' AND t LIKE ? ESCAPE '\\' AND t LIKE ? ESCAPE '\\')", []any{"% faith %", "% hope %", "% charity %"}},
+		// % and _ only match themselves in the LIKE search
+		{"100%", true, `"100%"`, "t LIKE ? ESCAPE '\\'", []any{"% 100\\% %"}},
+		{"a_b*", true, `"a_b"*`, "t LIKE ? ESCAPE '\\'", []any{"% a\\_b%"}},
+		// Operators only count in capitals, and not in quotes
+		{`"AND" or`, true, `("AND" AND "or")`, "(t LIKE ? ESCAPE '\\' AND t LIKE ? ESCAPE '\\')", []any{"% AND %", "% or %"}},
+	}
+
+	for _, test := range tests {
+		expr, err := ParseSearch(test.search, test.exact)
+		if err != nil {
+			t.Errorf("ParseSearch(%q, %v) gave an error: %v", test.search, test.exact, err)
+			continue
+		}
+		if fts := expr.fts(); fts != test.fts {
+			t.Errorf("ParseSearch(%q, %v).fts() = %s, want %s", test.search, test.exact, fts, test.fts)
+		}
+		sql, params := expr.sql("t")
+		if sql != test.sql || !reflect.DeepEqual(params, test.params) {
+			t.Errorf("ParseSearch(%q, %v).sql() = %s %q, want %s %q", test.search, test.exact, sql, params, test.sql, test.params)
+		}
+	}
+}
+
+
+func TestLikeSearch(t *testing.T) {
+	db := newTestBibleDb(t)
+	for id, text := range map[int]string{1: "It was 100% sure", 2: "It was 1000 sure", 3: "Loving kindness", 4: "the unloved", 5: "And, said he: Loved", 6: "a_b c", 7: "axb c"} {
+		if _, err := db.Exec("UPDATE bible SET text = ? WHERE id = ?", text, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		search	string
+		want	[]int
+	}{
+		{"100%", []int{1}},
+		{"a_b", []int{6}},
+		{"said", []int{5}},
+		{"love*", []int{5}},
+		{"lov*", []int{3, 5}},
+		{"kindness OR unloved", []int{3, 4}},
+	}
+
+	for _, test := range tests {
+		expr, err := ParseSearch(test.search, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		where, params := expr.sql(likeText())
+		rows, err := db.Query("SELECT id FROM bible WHERE "+where+" ORDER BY id", params...)
+		if err != nil {
+			t.Fatalf("LIKE search for %q: %v", test.search, err)
+		}
+		var got []int
+		for rows.Next() {
+			var id int
+			rows.Scan(&id)
+			got = append(got, id)
+		}
+		rows.Close()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("LIKE search for %q found %v, want %v", test.search, got, test.want)
+		}
+	}
+}
+
+
+func TestParseSearchErrors(t *testing.T) {
+	for _, search := range []string{
+		"",
+		`"so loved`,
+		"love AND",
+		"(love",
+		"love)",
+		"OR",
+		"NEAR hope",
+		"faith NEAR/x hope",
+		"faith NEAR (hope OR charity)",
+	} {
+		if expr, err := ParseSearch(search, false); err == nil {
+			t.Errorf("ParseSearch(%q) = %s, want an error", search, expr.fts())
+		}
+	}
+}
+
+
+func TestHasNear(t *testing.T) {
+	tests := []struct {
+		search	string
+		want	bool
+	}{
+		{"faith hope", false},
+		{"faith NEAR hope", true},
+		{"love OR (faith NEAR/3 hope)", true},
+		{"love NOT (faith NEAR/3 hope)", true},
+		{`"faith NEAR hope"`, false},
+	}
+
+	for _, test := range tests {
+		expr, err := ParseSearch(test.search, true)
+		if err != nil {
+			t.Errorf("ParseSearch(%q) gave an error: %v", test.search, err)
+			continue
+		}
+		if got := hasNear(expr); got != test.want {
+			t.Errorf("hasNear(%q) = %v, want %v", test.search, got, test.want)
+		}
+	}
+}
+
+
+func TestSearchTerms(t *testing.T) {
+	expr, err := ParseSearch(`love OR "so loved" NOT hate`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Anything after NOT isn't highlighted
+	want := []wordExpr{{word: "love"}, {word: "so"}, {word: "loved"}}
+	if got := expr.terms(); !reflect.DeepEqual(got, want) {
+		t.Errorf("terms() = %+v, want %+v", got, want)
+	}
+}
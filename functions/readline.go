@@ -0,0 +1,451 @@
+package functions
+
+import (
+	"os"
+	"io"
+	"fmt"
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"path/filepath"
+	"database/sql"
+	"golang.org/x/term"
+)
+
+
+// The prompts in interactive mode can be edited like a shell (emacs keys), have history (up/down) that is
+// saved between runs, and tab completes book names, then chapters, then verses.
+//
+//   left/right, ctrl-b/f ...... move a letter        home/end, ctrl-a/e .. start/end of the line
+//   ctrl-left/right ........... move a word          up/down, ctrl-p/n ... history
+//   backspace, delete/ctrl-d .. delete a letter      ctrl-w .............. delete a word
+//   ctrl-u / ctrl-k ........... delete to the start/end of the line
+//   tab ....................... complete             ctrl-l .............. clear the screen
+//   ctrl-c, or ctrl-d on an empty line ............. quit
+
+
+// The most lines of history that are kept
+const maxHistory = 1000
+
+
+// Everything reads stdin through this one reader when it isn't a terminal (ie piped in). A new bufio.Reader
+// for every line would read ahead and throw away whatever it didn't use, so lines went missing.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+
+// The history, loaded from the file the first time it's needed
+var inputHistory []string
+var historyLoaded bool
+
+
+// The database tab completion looks up chapters and verses in. Completion is off until EnableCompletion sets it
+var completionDb *sql.DB
+
+
+// For completing chapters, ie "John 3" and verses, ie "John 3:1" or "John 3 1"
+var chapterCompletion = regexp.MustCompile(`^(.+?)\s+(\d*)$`)
+var verseCompletion = regexp.MustCompile(`^(.+?)\s+(\d+)(:|\s+)(\d*)$`)
+
+
+// EnableCompletion turns on tab completion of books, chapters and verses (chapters and verses come from db)
+func EnableCompletion(db *sql.DB) {
+	completionDb = db
+}
+
+
+// GetHistoryPath gives back where the prompt history is saved (~/.local/share/bible/prompt-history)
+func GetHistoryPath() string {
+	return filepath.Join(filepath.Dir(GetDataFilePath()), "prompt-history")
+}
+
+
+// ReadLine asks for a line. In a terminal it can be edited, and if remember is true it goes in the history.
+// If there is nothing left to read (ctrl-d, ctrl-c or the end of a piped file) the error is io.EOF.
+func ReadLine(prompt string, remember bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !IsTerminal() {
+		fmt.Print(prompt)
+		line, err := stdinReader.ReadString('\n')
+		// The last line might not have a newline on the end
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, oldState)
+
+	e := &lineEditor{prompt: prompt}
+	if remember {
+		loadHistory()
+		e.history = inputHistory
+	}
+	e.historyPos = len(e.history)
+
+	line, err := e.run()
+	if err == nil && remember {
+		addHistory(line)
+	}
+	return line, err
+}
+
+
+// For the yes/no and other questions. Gives back the answer without spaces on the ends, or "" if there's nothing left to read
+func askLine(prompt string) string {
+	line, _ := askLineOk(prompt)
+	return line
+}
+
+
+// Same as askLine, but ok is false if there was nothing left to read. This is for questions where just pressing
+// enter means something (ie the default bookmark), so ctrl-d can still cancel
+func askLineOk(prompt string) (string, bool) {
+	line, err := ReadLine(prompt, false)
+	if err != nil {
+		fmt.Println()
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+
+// A line being edited
+type lineEditor struct {
+	prompt		string
+	line		[]rune
+	pos			int			// Where the cursor is in line
+	history		[]string
+	historyPos	int			// Which history line is showing. len(history) is the one being typed
+	typed		[]rune		// What was being typed before going up into the history
+}
+
+
+// Reads keys until enter (or ctrl-c/ctrl-d), editing the line as it goes
+func (e *lineEditor) run() (string, error) {
+	e.redraw()
+
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return "", err
+		}
+
+		for _, key := range splitKeys(string(buf[:n])) {
+			switch key {
+			case "\r", "\n":
+				fmt.Print("\r\n")
+				return string(e.line), nil
+			case "\x03": // ctrl-c
+				fmt.Print("^C\r\n")
+				return "", io.EOF
+			case "\x04": // ctrl-d quits on an empty line, otherwise it's delete
+				if len(e.line) == 0 {
+					fmt.Print("\r\n")
+					return "", io.EOF
+				}
+				e.delete(e.pos, e.pos+1)
+			case "\x7f", "\x08": // backspace
+				e.delete(e.pos-1, e.pos)
+			case "\x1b[3~": // delete
+				e.delete(e.pos, e.pos+1)
+			case "\x1b[D", "\x02":
+				e.pos = max(e.pos-1, 0)
+			case "\x1b[C", "\x06":
+				e.pos = min(e.pos+1, len(e.line))
+			case "\x1b[1;5D", "\x1b[1;3D":
+				e.pos = e.wordStart()
+			case "\x1b[1;5C", "\x1b[1;3C":
+				e.pos = e.wordEnd()
+			case "\x1b[H", "\x1b[1~", "\x01":
+				e.pos = 0
+			case "\x1b[F", "\x1b[4~", "\x05":
+				e.pos = len(e.line)
+			case "\x0b": // ctrl-k
+				e.delete(e.pos, len(e.line))
+			case "\x15": // ctrl-u
+				e.delete(0, e.pos)
+			case "\x17": // ctrl-w
+				e.delete(e.wordStart(), e.pos)
+			case "\x1b[A", "\x10":
+				e.showHistory(e.historyPos - 1)
+			case "\x1b[B", "\x0e":
+				e.showHistory(e.historyPos + 1)
+			case "\x0c": // ctrl-l
+				fmt.Print("\033[H\033[2J")
+			case "\t":
+				e.complete()
+			default:
+				for _, c := range key {
+					if unicode.IsPrint(c) {
+						e.insert(string(c))
+					}
+				}
+			}
+			e.redraw()
+		}
+	}
+}
+
+
+// Draws the prompt and the line again, and puts the cursor back where it goes
+func (e *lineEditor) redraw() {
+	fmt.Print("\r" + e.prompt + string(e.line) + "\033[K\r")
+	if column := textWidth(e.prompt) + e.pos; column > 0 {
+		fmt.Printf("\033[%dC", column)
+	}
+}
+
+
+func (e *lineEditor) insert(text string) {
+	runes := []rune(text)
+	e.line = append(e.line[:e.pos], append(runes, e.line[e.pos:]...)...)
+	e.pos += len(runes)
+}
+
+
+// Deletes from start up to (not including) end
+func (e *lineEditor) delete(start int, end int) {
+	start = max(start, 0)
+	end = min(end, len(e.line))
+	if start >= end {
+		return
+	}
+	e.line = append(e.line[:start], e.line[end:]...)
+	if e.pos > end {
+		e.pos -= end - start
+	} else if e.pos > start {
+		e.pos = start
+	}
+}
+
+
+// Where the word before the cursor starts
+func (e *lineEditor) wordStart() int {
+	i := e.pos
+	for i > 0 && unicode.IsSpace(e.line[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(e.line[i-1]) {
+		i--
+	}
+	return i
+}
+
+
+// Where the word after the cursor ends
+func (e *lineEditor) wordEnd() int {
+	i := e.pos
+	for i < len(e.line) && unicode.IsSpace(e.line[i]) {
+		i++
+	}
+	for i < len(e.line) && !unicode.IsSpace(e.line[i]) {
+		i++
+	}
+	return i
+}
+
+
+// Goes to a line in the history. Going past the newest one comes back to what was being typed
+func (e *lineEditor) showHistory(pos int) {
+	if pos < 0 || pos > len(e.history) || pos == e.historyPos {
+		return
+	}
+	if e.historyPos == len(e.history) {
+		e.typed = e.line
+	}
+
+	e.historyPos = pos
+	if pos == len(e.history) {
+		e.line = e.typed
+	} else {
+		e.line = []rune(e.history[pos])
+	}
+	e.pos = len(e.line)
+}
+
+
+// Tab. If there's only one thing it could be, fill it in. If there are a few, fill in as much as they
+// have in common, and if that doesn't add anything, list them
+func (e *lineEditor) complete() {
+	before := string(e.line[:e.pos])
+	start, candidates, suffix := completeReference(before)
+	if len(candidates) == 0 {
+		fmt.Print("\a")
+		return
+	}
+
+	typed := []rune(before[start:])
+	replacement := ""
+	if len(candidates) == 1 {
+		replacement = candidates[0] + suffix
+	} else if common := commonPrefix(candidates); len([]rune(common)) > len(typed) {
+		replacement = common
+	} else {
+		fmt.Print("\r\n")
+		printCandidates(candidates)
+		return
+	}
+
+	e.delete(e.pos-len(typed), e.pos)
+	e.insert(replacement)
+}
+
+
+// Works out what can go at the end of the text. It gives back where the bit being completed starts,
+// what it could be, and what goes after it if it's the only one (ie ":" after a chapter)
+func completeReference(text string) (int, []string, string) {
+	// Try from the start of each word, so "a John 3" (a note) still completes
+	for _, start := range wordStarts(text) {
+		rest := text[start:]
+
+		if completionDb != nil {
+			if m := verseCompletion.FindStringSubmatch(rest); m != nil {
+				if book, err := ResolveBook(m[1]); err == nil {
+					chapter, _ := strconv.Atoi(m[2])
+					if chapter >= 1 && chapter <= GetAllChaptersInBook(completionDb, book) {
+						verses := GetAllVersesInChapter(completionDb, book, m[2])
+						return len(text) - len(m[4]), numbersStartingWith(verses, m[4]), ""
+					}
+				}
+			}
+
+			if m := chapterCompletion.FindStringSubmatch(rest); m != nil {
+				if book, err := ResolveBook(m[1]); err == nil {
+					chapters := GetAllChaptersInBook(completionDb, book)
+					return len(text) - len(m[2]), numbersStartingWith(chapters, m[2]), ":"
+				}
+			}
+		}
+
+		if books := booksStartingWith(rest); len(books) > 0 {
+			return start, books, " "
+		}
+	}
+	return len(text), nil, ""
+}
+
+
+// Where each word starts, first to last
+func wordStarts(text string) []int {
+	var starts []int
+	for i, c := range text {
+		if !unicode.IsSpace(c) && (i == 0 || text[i-1] == ' ') {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+
+// The books that start with what was typed. Case and spaces don't matter, so "1jo" and "song of" both work
+func booksStartingWith(typed string) []string {
+	normalized := normalizeBookName(typed)
+	if normalized == "" {
+		return nil
+	}
+
+	var books []string
+	for _, book := range allBooks {
+		if strings.HasPrefix(normalizeBookName(book), normalized) {
+			books = append(books, book)
+		}
+	}
+	return books
+}
+
+
+// The numbers from 1 to count that start with what was typed, ie "1" out of 21 is 1, 10, 11, ... 19
+func numbersStartingWith(count int, typed string) []string {
+	var numbers []string
+	for n := 1; n <= count; n++ {
+		if number := strconv.Itoa(n); strings.HasPrefix(number, typed) {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+
+// The part at the start that all of them have
+func commonPrefix(list []string) string {
+	prefix := []rune(list[0])
+	for _, item := range list[1:] {
+		runes := []rune(item)
+		i := 0
+		for i < len(prefix) && i < len(runes) && prefix[i] == runes[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+	return string(prefix)
+}
+
+
+// Lists the completions in columns under the prompt (the terminal is raw, so it needs \r\n)
+func printCandidates(candidates []string) {
+	width := 0
+	for _, c := range candidates {
+		width = max(width, textWidth(c))
+	}
+	width += 2
+	columns := max(termWidth()/width, 1)
+
+	for i, c := range candidates {
+		fmt.Print(c + strings.Repeat(" ", width-textWidth(c)))
+		if (i+1)%columns == 0 || i == len(candidates)-1 {
+			fmt.Print("\r\n")
+		}
+	}
+}
+
+
+// Reads the history file. If it has got too long, it gets cut down to the newest lines
+func loadHistory() {
+	if historyLoaded {
+		return
+	}
+	historyLoaded = true
+
+	data, err := os.ReadFile(GetHistoryPath())
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			inputHistory = append(inputHistory, line)
+		}
+	}
+
+	if len(inputHistory) > maxHistory {
+		inputHistory = inputHistory[len(inputHistory)-maxHistory:]
+		os.WriteFile(GetHistoryPath(), []byte(strings.Join(inputHistory, "\n")+"\n"), 0644)
+	}
+}
+
+
+// Adds a line to the history, and to the end of the file. Not empty lines, or the same thing twice in a row
+func addHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(inputHistory) > 0 && inputHistory[len(inputHistory)-1] == line) {
+		return
+	}
+	inputHistory = append(inputHistory, line)
+
+	path := GetHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
@@ -0,0 +1,141 @@
+package functions
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+
+// Makes the questions read their answers from input instead of the terminal
+func useTestInput(t *testing.T, input string) {
+	t.Helper()
+	old := stdinReader
+	stdinReader = bufio.NewReader(strings.NewReader(input))
+	t.Cleanup(func() { stdinReader = old })
+}
+
+
+func TestReadLineNotATerminal(t *testing.T) {
+	useTestInput(t, "John 3:16\r\n  love  \nno newline")
+
+	var lines []string
+	captureOutput(t, func() {
+		for {
+			line, err := ReadLine("> ", false)
+			if err != nil {
+				break
+			}
+			lines = append(lines, line)
+		}
+	})
+	if want := []string{"John 3:16", "  love  ", "no newline"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("ReadLine() read %q, want %q", lines, want)
+	}
+
+	// Interactive mode quits when there's nothing left to read
+	if got := GetUserInput("> "); !reflect.DeepEqual(got, []string{"q"}) {
+		t.Errorf("GetUserInput() with nothing left = %q, want q", got)
+	}
+}
+
+
+func TestCompleteReference(t *testing.T) {
+	EnableCompletion(newTestBibleDb(t))
+	t.Cleanup(func() { EnableCompletion(nil) })
+
+	tests := []struct {
+		text		string
+		start		int
+		candidates	[]string
+		suffix		string
+	}{
+		{"Gene", 0, []string{"Genesis"}, " "},
+		{"gen", 0, []string{"Genesis"}, " "},
+		{"1 jo", 0, []string{"1 John"}, " "},
+		{"song of", 0, []string{"Song of Solomon"}, " "},
+		{"Jo", 0, []string{"Joshua", "Job", "Joel", "Jonah", "John"}, " "},
+		// Chapters come from the database, and get a : after them
+		{"John ", 5, []string{"1", "2", "3"}, ":"},
+		{"Genesis 2", 8, []string{"2"}, ":"},
+		// Then the verses in that chapter
+		{"John 3:3", 7, []string{"3", "30", "31", "32", "33", "34", "35", "36"}, ""},
+		{"John 3 1", 7, []string{"1", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19"}, ""},
+		{"Jude 1:2", 7, []string{"2", "20", "21", "22", "23", "24", "25"}, ""},
+		// After a command, ie a note on a verse
+		{"a John 2:2", 9, []string{"2", "20", "21", "22", "23", "24", "25"}, ""},
+		// Nothing it could be
+		{"xyz", 3, nil, ""},
+		{"John 9:", 7, nil, ""},
+	}
+
+	for _, test := range tests {
+		start, candidates, suffix := completeReference(test.text)
+		if start != test.start || !reflect.DeepEqual(candidates, test.candidates) || suffix != test.suffix {
+			t.Errorf("completeReference(%q) = %d, %q, %q, want %d, %q, %q", test.text, start, candidates, suffix, test.start, test.candidates, test.suffix)
+		}
+	}
+}
+
+
+func TestCompleteBooksWithoutDb(t *testing.T) {
+	EnableCompletion(nil)
+
+	// Without a database there are no chapters or verses, but books still work
+	if _, candidates, _ := completeReference("John 3:"); candidates != nil {
+		t.Errorf("completeReference(\"John 3:\") without a db = %q, want nothing", candidates)
+	}
+	if _, candidates, _ := completeReference("Rev"); !reflect.DeepEqual(candidates, []string{"Revelation"}) {
+		t.Errorf("completeReference(\"Rev\") = %q, want Revelation", candidates)
+	}
+}
+
+
+func TestLineEditorComplete(t *testing.T) {
+	EnableCompletion(newTestBibleDb(t))
+	t.Cleanup(func() { EnableCompletion(nil) })
+
+	tests := []struct {
+		line	string
+		want	string
+	}{
+		{"Gene", "Genesis "},
+		{"go to 1 co", "go to 1 Corinthians "},
+		{"Genesis 2", "Genesis 2:"},
+		// Judges and Jude, so it fills in as much as they have in common
+		{"Ju", "Jud"},
+		{"Jude 1:25", "Jude 1:25"},
+	}
+
+	for _, test := range tests {
+		e := &lineEditor{line: []rune(test.line), pos: len([]rune(test.line))}
+		e.complete()
+		if got := string(e.line); got != test.want {
+			t.Errorf("complete(%q) = %q, want %q", test.line, got, test.want)
+		}
+		if e.pos != len(e.line) {
+			t.Errorf("complete(%q) left the cursor at %d, want the end (%d)", test.line, e.pos, len(e.line))
+		}
+	}
+}
+
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		list	[]string
+		want	string
+	}{
+		{[]string{"1 John", "1 Jo"}, "1 Jo"},
+		{[]string{"Job", "Joel", "John"}, "Jo"},
+		{[]string{"Genesis"}, "Genesis"},
+		{[]string{"Amos", "Acts"}, "A"},
+		{[]string{"Ruth", "Acts"}, ""},
+	}
+
+	for _, test := range tests {
+		if got := commonPrefix(test.list); got != test.want {
+			t.Errorf("commonPrefix(%q) = %q, want %q", test.list, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package functions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"database/sql"
+)
+
+
+// A single chunk of a reference, ie "John 3:16-18", "Gen 1:1-2:3" or "Malachi 4 - Matthew 1"
+// A Verse of 0 means start at the beginning of the chapter, an EndVerse of 0 means go to the end of the chapter.
+// A Chapter of 0 means the whole book was asked for (ie just "Genesis"), an EndChapter of 0 means go to the end of EndBook.
+type VerseRange struct {
+	Book		string
+	Chapter		int
+	Verse		int
+	EndBook		string
+	EndChapter	int
+	EndVerse	int
+}
+
+
+// A full reference. Something like "Ps 23; Ps 91:1-4" ends up as two ranges
+type Reference struct {
+	Ranges	[]VerseRange
+}
+
+
+// These books only have one chapter, so "Jude 5" means Jude 1:5 and not Jude chapter 5
+var singleChapterBooks = []string{"Obadiah", "Philemon", "2 John", "3 John", "Jude"}
+
+
+// Splits the book off the front of a reference. The book can start with a number (ie "1 John") and the
+// rest is whatever chapter/verse stuff comes after it.
+var bookAndSpec = regexp.MustCompile(`^([1-3]?\s*[A-Za-z][A-Za-z\s\.]*?)\s*(\d.*)?$`)
+
+
+// ParseReference takes a reference like "John 3:16", "1 Cor 13:4-7", "Gen 1:1-2:3", "Ps 23; Ps 91:1-4" or "Jude 5"
+// and turns it into a Reference. The old style of "Genesis 1 1" (book chapter verse with spaces) still works too.
+func ParseReference(input string) (Reference, error) {
+	var ref Reference
+
+	// Quotes used to be needed for "Song of Solomon" and numbered books, so just throw them away if they're there
+	input = strings.NewReplacer("\"", "", "'", "", "–", "-", "—", "-").Replace(input)
+
+	var book string
+	for _, segment := range strings.Split(input, ";") {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+
+		// A range that goes into another book, ie "Malachi 4 - Matthew 1"
+		if i := strings.Index(segment, "-"); i != -1 && strings.IndexFunc(segment[i+1:], isLetter) != -1 {
+			r, err := parseCrossBookRange(segment[:i], segment[i+1:])
+			if err != nil {
+				return Reference{}, err
+			}
+			book = r.EndBook
+			ref.Ranges = append(ref.Ranges, r)
+			continue
+		}
+
+		// If there are no letters in the segment, it uses the book from the last segment (ie "Ps 23; 91")
+		spec := segment
+		if strings.IndexFunc(segment, isLetter) != -1 {
+			match := bookAndSpec.FindStringSubmatch(segment)
+			if match == nil {
+				return Reference{}, fmt.Errorf("Can't understand reference \"%s\"", segment)
+			}
+
+			resolved, err := ResolveBook(match[1])
+			if err != nil {
+				return Reference{}, err
+			}
+			book = resolved
+			spec = match[2]
+		} else if book == "" {
+			return Reference{}, fmt.Errorf("Reference \"%s\" is missing a book", segment)
+		}
+
+		ranges, err := parseChapterVerse(book, spec)
+		if err != nil {
+			return Reference{}, err
+		}
+		ref.Ranges = append(ref.Ranges, ranges...)
+	}
+
+	if len(ref.Ranges) == 0 {
+		return Reference{}, fmt.Errorf("Please enter a reference, ie John 3:16")
+	}
+
+	return ref, nil
+}
+
+
+// This deals with everything after the book name, ie "3:16-18", "1-3", "1 1-5", or "16,18"
+func parseChapterVerse(book string, spec string) ([]VerseRange, error) {
+	spec = strings.TrimSpace(spec)
+
+	// Just a book on its own
+	if spec == "" {
+		return []VerseRange{{Book: book, EndBook: book}}, nil
+	}
+
+	// Old style "chapter verse" with a space instead of a colon
+	fields := strings.Fields(spec)
+	if len(fields) == 2 && !strings.Contains(spec, ":") && !strings.HasSuffix(fields[0], "-") && !strings.HasPrefix(fields[1], "-") {
+		spec = fields[0] + ":" + fields[1]
+	}
+	spec = strings.Join(strings.Fields(spec), "")
+
+	var ranges []VerseRange
+	// lastChapter is used for lists like "3:16,18" so that the 18 knows it's a verse in chapter 3
+	lastChapter := 0
+	for _, item := range strings.Split(spec, ",") {
+		if item == "" {
+			continue
+		}
+
+		r, err := parseRangeItem(book, item, lastChapter)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Verse > 0 {
+			lastChapter = r.EndChapter
+		} else {
+			lastChapter = 0
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("Can't understand \"%s\"", spec)
+	}
+
+	return ranges, nil
+}
+
+
+// Parses one piece of a comma list, ie "16-18" or "1:1-2:3"
+// If lastChapter isn't 0, a bare number is a verse in that chapter instead of a chapter.
+func parseRangeItem(book string, item string, lastChapter int) (VerseRange, error) {
+	r := VerseRange{Book: book, EndBook: book}
+
+	split := strings.Split(item, "-")
+	if len(split) > 2 {
+		return r, fmt.Errorf("Invalid range \"%s\", expected 'start-end'", item)
+	}
+
+	startChapter, startVerse, err := parseNumberPair(split[0])
+	if err != nil {
+		return r, err
+	}
+
+	// A bare number is a verse if we are already in a chapter or if the book only has one chapter
+	verseOnly := startVerse == -1 && (lastChapter > 0 || (isSingleChapterBook(book) && !strings.Contains(item, ":")))
+	if verseOnly {
+		r.Chapter = lastChapter
+		if r.Chapter == 0 {
+			r.Chapter = 1
+		}
+		r.Verse = startChapter
+	} else {
+		r.Chapter = startChapter
+		r.Verse = startVerse
+		if r.Verse == -1 {
+			r.Verse = 0
+		}
+	}
+
+	// No end, so the range is a single verse or a single chapter
+	if len(split) == 1 {
+		r.EndChapter = r.Chapter
+		r.EndVerse = r.Verse
+		return r, nil
+	}
+
+	endChapter, endVerse, err := parseNumberPair(split[1])
+	if err != nil {
+		return r, err
+	}
+
+	if endVerse != -1 {
+		// Something like 1:1-2:3
+		r.EndChapter = endChapter
+		r.EndVerse = endVerse
+	} else if r.Verse > 0 {
+		// Something like 3:16-18
+		r.EndChapter = r.Chapter
+		r.EndVerse = endChapter
+	} else {
+		// Something like 1-3 (chapters)
+		r.EndChapter = endChapter
+		r.EndVerse = 0
+	}
+
+	if r.EndChapter < r.Chapter || (r.EndChapter == r.Chapter && r.EndVerse != 0 && r.EndVerse < r.Verse) {
+		return r, fmt.Errorf("Invalid range \"%s\", the end is before the start", item)
+	}
+
+	return r, nil
+}
+
+
+// Parses a range where the end is in a different book, ie "Malachi 4" and "Matthew 1", or "Gen 50:20" and "Exod 1:7"
+func parseCrossBookRange(start string, end string) (VerseRange, error) {
+	startRef, err := ParseReference(start)
+	if err != nil {
+		return VerseRange{}, err
+	}
+	endRef, err := ParseReference(end)
+	if err != nil {
+		return VerseRange{}, err
+	}
+	if len(startRef.Ranges) != 1 || len(endRef.Ranges) != 1 {
+		return VerseRange{}, fmt.Errorf("Invalid range \"%s-%s\", expected 'start-end'", start, end)
+	}
+
+	s := startRef.Ranges[0]
+	e := endRef.Ranges[0]
+	r := VerseRange{
+		Book: s.Book,
+		Chapter: max(s.Chapter, 1),
+		Verse: s.Verse,
+		EndBook: e.EndBook,
+		EndChapter: e.EndChapter,
+		EndVerse: e.EndVerse,
+	}
+
+	// Make sure the end actually comes after the start
+	startIndex, endIndex := bookIndex(r.Book), bookIndex(r.EndBook)
+	if endIndex < startIndex || (endIndex == startIndex && r.EndChapter != 0 && r.EndChapter < r.Chapter) {
+		return VerseRange{}, fmt.Errorf("Invalid range \"%s-%s\", the end is before the start", strings.TrimSpace(start), strings.TrimSpace(end))
+	}
+
+	return r, nil
+}
+
+
+// Parses "3:16" into 3, 16 or "3" into 3, -1 (-1 meaning there was no verse)
+func parseNumberPair(s string) (int, int, error) {
+	split := strings.Split(s, ":")
+	if len(split) > 2 {
+		return 0, 0, fmt.Errorf("Invalid reference \"%s\"", s)
+	}
+
+	first, err := strconv.Atoi(split[0])
+	if err != nil || first < 1 {
+		return 0, 0, fmt.Errorf("Invalid number \"%s\"", split[0])
+	}
+
+	if len(split) == 1 {
+		return first, -1, nil
+	}
+
+	second, err := strconv.Atoi(split[1])
+	if err != nil || second < 1 {
+		return 0, 0, fmt.Errorf("Invalid number \"%s\"", split[1])
+	}
+
+	return first, second, nil
+}
+
+
+// Checks if a book only has one chapter
+func isSingleChapterBook(book string) bool {
+	for _, b := range singleChapterBooks {
+		if b == book {
+			return true
+		}
+	}
+	return false
+}
+
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+
+// Gives back a range in the usual format, ie "John 3:16-18", "Genesis 1:1-2:3" or "Malachi 4 - Matthew 1"
+func (r VerseRange) String() string {
+	// Ranges that go into a different book are just the start and the end with a dash between them
+	if r.EndBook != "" && r.EndBook != r.Book {
+		start := VerseRange{Book: r.Book, Chapter: r.Chapter, Verse: r.Verse, EndBook: r.Book, EndChapter: r.Chapter, EndVerse: r.Verse}
+		end := VerseRange{Book: r.EndBook, Chapter: r.EndChapter, Verse: r.EndVerse, EndBook: r.EndBook, EndChapter: r.EndChapter, EndVerse: r.EndVerse}
+		return start.String() + " - " + end.String()
+	}
+
+	switch {
+	case r.Chapter == 0:
+		return r.Book
+	case r.Verse == 0 && r.EndVerse == 0 && r.EndChapter == r.Chapter:
+		return fmt.Sprintf("%s %d", r.Book, r.Chapter)
+	case r.Verse == 0 && r.EndVerse == 0:
+		return fmt.Sprintf("%s %d-%d", r.Book, r.Chapter, r.EndChapter)
+	case r.EndChapter == r.Chapter && r.EndVerse == r.Verse:
+		return fmt.Sprintf("%s %d:%d", r.Book, r.Chapter, r.Verse)
+	case r.EndChapter == r.Chapter && r.EndVerse != 0:
+		return fmt.Sprintf("%s %d:%d-%d", r.Book, r.Chapter, r.Verse, r.EndVerse)
+	}
+
+	start := fmt.Sprintf("%s %d:%d", r.Book, r.Chapter, max(r.Verse, 1))
+	if r.EndVerse == 0 {
+		return fmt.Sprintf("%s-%d", start, r.EndChapter)
+	}
+	return fmt.Sprintf("%s-%d:%d", start, r.EndChapter, r.EndVerse)
+}
+
+
+// Gives back the whole reference, ie "Psalms 23; Psalms 91:1-4"
+func (ref Reference) String() string {
+	var parts []string
+	for _, r := range ref.Ranges {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+
+// GetIdRange gives back the id of the first and last verse in a range. Because the ids go in order through the
+// whole bible, everything in between is the passage, even if it goes over chapters or books.
+func GetIdRange(db *sql.DB, r VerseRange) (int, int, error) {
+	var start, end sql.NullInt64
+
+	// Find the first verse
+	switch {
+	case r.Chapter == 0:
+		db.QueryRow("SELECT MIN(id) FROM bible WHERE bookName = ?", r.Book).Scan(&start)
+	case r.Verse == 0:
+		db.QueryRow("SELECT MIN(id) FROM bible WHERE bookName = ? AND chapter = ?", r.Book, r.Chapter).Scan(&start)
+	default:
+		db.QueryRow("SELECT id FROM bible WHERE bookName = ? AND chapter = ? AND verse = ?", r.Book, r.Chapter, r.Verse).Scan(&start)
+	}
+	if !start.Valid {
+		if r.Verse == 0 {
+			return 0, 0, notFound("Can't find chapter %d in book \"%s\"", r.Chapter, r.Book)
+		}
+		return 0, 0, notFound("Can't find %s %d:%d", r.Book, r.Chapter, r.Verse)
+	}
+
+	endBook := r.EndBook
+	if endBook == "" {
+		endBook = r.Book
+	}
+
+	// Find the last verse
+	switch {
+	case r.EndChapter == 0:
+		db.QueryRow("SELECT MAX(id) FROM bible WHERE bookName = ?", endBook).Scan(&end)
+	case r.EndVerse != 0:
+		db.QueryRow("SELECT id FROM bible WHERE bookName = ? AND chapter = ? AND verse = ?", endBook, r.EndChapter, r.EndVerse).Scan(&end)
+	}
+	// If the end verse doesn't exist (ie John 3:16-99), just go to the end of the chapter
+	if !end.Valid && r.EndChapter != 0 {
+		db.QueryRow("SELECT MAX(id) FROM bible WHERE bookName = ? AND chapter = ?", endBook, r.EndChapter).Scan(&end)
+	}
+	if !end.Valid {
+		return 0, 0, notFound("Can't find chapter %d in book \"%s\"", r.EndChapter, endBook)
+	}
+
+	if end.Int64 < start.Int64 {
+		return 0, 0, fmt.Errorf("Invalid range \"%s\", the end is before the start", r)
+	}
+
+	return int(start.Int64), int(end.Int64), nil
+}
+
+
+// GetVersesInRange gives back every verse in the range, in order
+func GetVersesInRange(db *sql.DB, r VerseRange) ([]Bible, error) {
+	start, end, err := GetIdRange(db, r)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT id, bookName, book, chapter, verse, text FROM bible WHERE id BETWEEN ? AND ? ORDER BY id", start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var verses []Bible
+	for rows.Next() {
+		var verse Bible
+		if err := rows.Scan(&verse.ID, &verse.BookName, &verse.Book, &verse.Chapter, &verse.Verse, &verse.Text); err != nil {
+			return nil, err
+		}
+		verses = append(verses, verse)
+	}
+
+	return verses, rows.Err()
+}
+
+
+// PrintRange prints every verse in a range. ie "John 3:16-18", "Genesis 1:26-2:3" or "Malachi 4 - Matthew 1"
+func PrintRange(db *sql.DB, r VerseRange) error {
+	verses, err := GetVersesInRange(db, r)
+	if err != nil {
+		return err
+	}
+
+	// Only need chapter headings if whole chapters were asked for, and there is more than one of them.
+	// Not for json/csv/etc though, those are just the verses
+	headings := IsTextOutput() && r.Verse == 0 && r.EndVerse == 0 && (r.EndBook != r.Book || r.EndChapter != r.Chapter)
+
+	for i, verse := range verses {
+		if headings && (i == 0 || verse.Chapter != verses[i-1].Chapter || verse.BookName != verses[i-1].BookName) {
+			fmt.Printf("%s Chapter %d\n\n", verse.BookName, verse.Chapter)
+		}
+		printBibleVerse(verse)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		input	string
+		want	[]VerseRange
+	}{
+		{"John 3:16", []VerseRange{{Book: "John", Chapter: 3, Verse: 16, EndBook: "John", EndChapter: 3, EndVerse: 16}}},
+		{"John 3:16-18", []VerseRange{{Book: "John", Chapter: 3, Verse: 16, EndBook: "John", EndChapter: 3, EndVerse: 18}}},
+		{"Genesis 1 1", []VerseRange{{Book: "Genesis", Chapter: 1, Verse: 1, EndBook: "Genesis", EndChapter: 1, EndVerse: 1}}},
+		{"1 Cor 13:4-7", []VerseRange{{Book: "1 Corinthians", Chapter: 13, Verse: 4, EndBook: "1 Corinthians", EndChapter: 13, EndVerse: 7}}},
+		{"Gen 1:1-2:3", []VerseRange{{Book: "Genesis", Chapter: 1, Verse: 1, EndBook: "Genesis", EndChapter: 2, EndVerse: 3}}},
+		{"Romans 8", []VerseRange{{Book: "Romans", Chapter: 8, EndBook: "Romans", EndChapter: 8}}},
+		{"Psalms 1-3", []VerseRange{{Book: "Psalms", Chapter: 1, EndBook: "Psalms", EndChapter: 3}}},
+		{"Genesis", []VerseRange{{Book: "Genesis", EndBook: "Genesis"}}},
+		{"Jude 5", []VerseRange{{Book: "Jude", Chapter: 1, Verse: 5, EndBook: "Jude", EndChapter: 1, EndVerse: 5}}},
+		{"\"Song of Solomon\" 2:4", []VerseRange{{Book: "Song of Solomon", Chapter: 2, Verse: 4, EndBook: "Song of Solomon", EndChapter: 2, EndVerse: 4}}},
+		{"Ps 23; Ps 91:1-4", []VerseRange{
+			{Book: "Psalms", Chapter: 23, EndBook: "Psalms", EndChapter: 23},
+			{Book: "Psalms", Chapter: 91, Verse: 1, EndBook: "Psalms", EndChapter: 91, EndVerse: 4},
+		}},
+		// No book in the second part, so it's still Psalms
+		{"Ps 23; 91", []VerseRange{
+			{Book: "Psalms", Chapter: 23, EndBook: "Psalms", EndChapter: 23},
+			{Book: "Psalms", Chapter: 91, EndBook: "Psalms", EndChapter: 91},
+		}},
+		// The 18 is a verse in chapter 3, not chapter 18
+		{"John 3:16,18", []VerseRange{
+			{Book: "John", Chapter: 3, Verse: 16, EndBook: "John", EndChapter: 3, EndVerse: 16},
+			{Book: "John", Chapter: 3, Verse: 18, EndBook: "John", EndChapter: 3, EndVerse: 18},
+		}},
+	}
+
+	for _, test := range tests {
+		ref, err := ParseReference(test.input)
+		if err != nil {
+			t.Errorf("ParseReference(%q) gave an error: %v", test.input, err)
+			continue
+		}
+		if len(ref.Ranges) != len(test.want) {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", test.input, ref.Ranges, test.want)
+			continue
+		}
+		for i := range test.want {
+			if ref.Ranges[i] != test.want[i] {
+				t.Errorf("ParseReference(%q) range %d = %+v, want %+v", test.input, i, ref.Ranges[i], test.want[i])
+			}
+		}
+	}
+}
+
+
+func TestParseReferenceErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		";",
+		"Jhon 3:16",
+		"John 3:18-16",
+		"John 3-1",
+		"John 0:1",
+		"John 3:16-17-18",
+		"Matthew 1 - Malachi 4",
+	} {
+		if ref, err := ParseReference(input); err == nil {
+			t.Errorf("ParseReference(%q) = %+v, want an error", input, ref.Ranges)
+		}
+	}
+}
+
+
+func TestParseRangeItem(t *testing.T) {
+	tests := []struct {
+		book		string
+		item		string
+		lastChapter	int
+		want		VerseRange
+	}{
+		{"John", "3", 0, VerseRange{Book: "John", Chapter: 3, EndBook: "John", EndChapter: 3}},
+		{"John", "3:16", 0, VerseRange{Book: "John", Chapter: 3, Verse: 16, EndBook: "John", EndChapter: 3, EndVerse: 16}},
+		{"John", "1-3", 0, VerseRange{Book: "John", Chapter: 1, EndBook: "John", EndChapter: 3}},
+		{"John", "3:16-18", 0, VerseRange{Book: "John", Chapter: 3, Verse: 16, EndBook: "John", EndChapter: 3, EndVerse: 18}},
+		{"John", "3:16-4:2", 0, VerseRange{Book: "John", Chapter: 3, Verse: 16, EndBook: "John", EndChapter: 4, EndVerse: 2}},
+		// After "3:16," a bare number is a verse in chapter 3
+		{"John", "18", 3, VerseRange{Book: "John", Chapter: 3, Verse: 18, EndBook: "John", EndChapter: 3, EndVerse: 18}},
+		{"John", "18-20", 3, VerseRange{Book: "John", Chapter: 3, Verse: 18, EndBook: "John", EndChapter: 3, EndVerse: 20}},
+		// Books with one chapter only have verses
+		{"Jude", "5", 0, VerseRange{Book: "Jude", Chapter: 1, Verse: 5, EndBook: "Jude", EndChapter: 1, EndVerse: 5}},
+		{"Jude", "3-5", 0, VerseRange{Book: "Jude", Chapter: 1, Verse: 3, EndBook: "Jude", EndChapter: 1, EndVerse: 5}},
+		{"Jude", "1:5", 0, VerseRange{Book: "Jude", Chapter: 1, Verse: 5, EndBook: "Jude", EndChapter: 1, EndVerse: 5}},
+	}
+
+	for _, test := range tests {
+		got, err := parseRangeItem(test.book, test.item, test.lastChapter)
+		if err != nil {
+			t.Errorf("parseRangeItem(%q, %q, %d) gave an error: %v", test.book, test.item, test.lastChapter, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseRangeItem(%q, %q, %d) = %+v, want %+v", test.book, test.item, test.lastChapter, got, test.want)
+		}
+	}
+
+	for _, item := range []string{"a", "3:x", "1-2-3", "3:1:2", "5-2", "3:16-3:10"} {
+		if got, err := parseRangeItem("John", item, 0); err == nil {
+			t.Errorf("parseRangeItem(\"John\", %q, 0) = %+v, want an error", item, got)
+		}
+	}
+}
+
+
+func TestCrossBookRanges(t *testing.T) {
+	tests := []struct {
+		input	string
+		want	VerseRange
+		str		string
+	}{
+		{"Malachi 4 - Matthew 1", VerseRange{Book: "Malachi", Chapter: 4, EndBook: "Matthew", EndChapter: 1}, "Malachi 4 - Matthew 1"},
+		{"Gen 50:20 - Exod 1:7", VerseRange{Book: "Genesis", Chapter: 50, Verse: 20, EndBook: "Exodus", EndChapter: 1, EndVerse: 7}, "Genesis 50:20 - Exodus 1:7"},
+		// A whole book at the end goes to the end of it
+		{"Ruth - 1 Samuel", VerseRange{Book: "Ruth", Chapter: 1, EndBook: "1 Samuel"}, "Ruth 1 - 1 Samuel"},
+		{"Jude 3 – Revelation 1:3", VerseRange{Book: "Jude", Chapter: 1, Verse: 3, EndBook: "Revelation", EndChapter: 1, EndVerse: 3}, "Jude 1:3 - Revelation 1:3"},
+	}
+
+	for _, test := range tests {
+		ref, err := ParseReference(test.input)
+		if err != nil {
+			t.Errorf("ParseReference(%q) gave an error: %v", test.input, err)
+			continue
+		}
+		if len(ref.Ranges) != 1 || ref.Ranges[0] != test.want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", test.input, ref.Ranges, test.want)
+			continue
+		}
+		if str := ref.String(); str != test.str {
+			t.Errorf("ParseReference(%q).String() = %q, want %q", test.input, str, test.str)
+		}
+	}
+}
+
+
+func TestVerseRangeString(t *testing.T) {
+	for _, input := range []string{"John 3:16", "John 3:16-18", "Genesis 1:1-2:3", "Romans 8", "Psalms 1-3", "Genesis"} {
+		ref, err := ParseReference(input)
+		if err != nil {
+			t.Errorf("ParseReference(%q) gave an error: %v", input, err)
+			continue
+		}
+		if str := ref.String(); str != input {
+			t.Errorf("ParseReference(%q).String() = %q", input, str)
+		}
+	}
+}
+
+
+func TestGetIdRange(t *testing.T) {
+	db := newTestBibleDb(t)
+
+	// Genesis 1 is 1-31, Genesis 2 is 32-56, John 1 is 57-107, John 2 is 108-132, John 3 is 133-168 and Jude is 169-193
+	tests := []struct {
+		input		string
+		start, end	int
+	}{
+		{"John 3:16", 148, 148},
+		{"John 3:16-18", 148, 150},
+		{"John 3", 133, 168},
+		{"John 1-2", 57, 132},
+		{"John", 57, 168},
+		{"Genesis 1:30-2:2", 30, 33},
+		{"John 2:25 - Jude 2", 132, 170},
+		{"Genesis 2 - John 1", 32, 107},
+		{"John 3 - Jude", 133, 193},
+		// The end is past the end of the chapter, so it stops at the end of it
+		{"John 3:30-99", 162, 168},
+	}
+
+	for _, test := range tests {
+		ref, err := ParseReference(test.input)
+		if err != nil {
+			t.Errorf("ParseReference(%q) gave an error: %v", test.input, err)
+			continue
+		}
+		start, end, err := GetIdRange(db, ref.Ranges[0])
+		if err != nil {
+			t.Errorf("GetIdRange(%q) gave an error: %v", test.input, err)
+			continue
+		}
+		if start != test.start || end != test.end {
+			t.Errorf("GetIdRange(%q) = %d-%d, want %d-%d", test.input, start, end, test.start, test.end)
+		}
+	}
+
+	for _, input := range []string{"John 4", "John 3:99", "Genesis 3 - John 1", "John 1 - Jude 2:1", "Romans 1"} {
+		ref, err := ParseReference(input)
+		if err != nil {
+			t.Errorf("ParseReference(%q) gave an error: %v", input, err)
+			continue
+		}
+		if start, end, err := GetIdRange(db, ref.Ranges[0]); err == nil {
+			t.Errorf("GetIdRange(%q) = %d-%d, want an error", input, start, end)
+		}
+	}
+}
+
+
+func TestGetVersesInRange(t *testing.T) {
+	db := newTestBibleDb(t)
+
+	ref, err := ParseReference("John 3:35 - Jude 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verses, err := GetVersesInRange(db, ref.Ranges[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, verse := range verses {
+		got = append(got, fmt.Sprintf("%s %d:%d", verse.BookName, verse.Chapter, verse.Verse))
+	}
+	want := "John 3:35, John 3:36, Jude 1:1, Jude 1:2"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("GetVersesInRange(John 3:35 - Jude 2) = %s, want %s", strings.Join(got, ", "), want)
+	}
+}
+
+
+func TestPrintRangeError(t *testing.T) {
+	db := newTestBibleDb(t)
+	ref, err := ParseReference("John 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The caller decides where the error goes (stderr, for json), so nothing gets printed
+	var printErr error
+	if got := captureOutput(t, func() { printErr = PrintRange(db, ref.Ranges[0]) }); printErr == nil || got != "" {
+		t.Errorf("PrintRange(John 5) = %v and printed %q, want an error and nothing printed", printErr, got)
+	}
+}
@@ -0,0 +1,278 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"regexp"
+	"sync"
+	"strings"
+	"database/sql"
+)
+
+
+// Options for searching (bible -s)
+type SearchOptions struct {
+	Exact	bool	// Only whole words (-e). Otherwise words match anything that starts with them, ie love matches loved
+	Rank	bool	// Best matches first instead of in bible order
+	In		string	// Only search part of the bible, ie "NT", "Gospels", "Romans" or "Isaiah 40-66" (see ParseScope)
+	Limit	int		// Only give back this many results (0 means all of them)
+	Offset	int		// Skip this many results first, for getting the next page
+	Regex	bool	// The search is a regular expression (--regex), ie \b(lov(e|ed|eth))\b
+}
+
+
+// How many search matches there were in a book
+type BookCount struct {
+	BookName	string	`json:"bookName"`
+	Count		int		`json:"count"`
+}
+
+
+// The full text search index. It's an fts5 table that points back at the bible table, so the text isn't stored twice
+const createSearchIndex = `CREATE VIRTUAL TABLE IF NOT EXISTS bible_fts USING fts5(text, content='bible', content_rowid='id')`
+
+
+// Searching without the index still works, but NEAR and --rank can't be done properly. That only gets said once,
+// even if there are a lot of searches (ie bible serve)
+var warnNoSearchIndex sync.Once
+
+
+// EnsureSearchIndex makes sure the full text search index is there, and builds it if it isn't.
+// tool/json_to_sqlite.go builds it ahead of time, so this is mostly for translations that were made without it.
+// It gives back an error if fts5 isn't available (the program has to be built with "-tags sqlite_fts5"), or if
+// the database is read only (the embedded one is, see OpenTranslation) and doesn't have the index already
+func EnsureSearchIndex(db *sql.DB) error {
+	var name string
+	err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'bible_fts'").Scan(&name)
+	if err == sql.ErrNoRows {
+		if _, err := db.Exec(createSearchIndex); err != nil {
+			return err
+		}
+		// Fill the index from the bible table
+		if _, err := db.Exec("INSERT INTO bible_fts(bible_fts) VALUES('rebuild')"); err != nil {
+			db.Exec("DROP TABLE IF EXISTS bible_fts")
+			return err
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// The table is there, but make sure this build can actually read it
+	_, err = db.Exec("SELECT rowid FROM bible_fts LIMIT 0")
+	return err
+}
+
+
+// BuildSearchQuery turns what the user searched for into a query. The query gives back
+// id, bookName, book, chapter, verse, text for every verse that matches. See ParseSearch for what a search can have in it.
+// It uses the full text search index if it can, otherwise it falls back to LIKE (which is slower and not as smart)
+func BuildSearchQuery(db *sql.DB, term string, opts SearchOptions) (string, []any, error) {
+	// Only search part of the bible. This is done with the ids, since every book/chapter/range is a run of ids
+	scope, scopeParams, err := scopeSql(db, opts.In)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// For paging through the results with --limit and --offset. A limit of -1 is no limit in sqlite
+	page := ""
+	var pageParams []any
+	if opts.Limit > 0 || opts.Offset > 0 {
+		page = " LIMIT ? OFFSET ?"
+		pageParams = []any{-1, max(opts.Offset, 0)}
+		if opts.Limit > 0 {
+			pageParams[0] = opts.Limit
+		}
+	}
+
+	// Regular expressions don't go through the search parser, they go straight to the regexp function (see driver.go)
+	if opts.Regex {
+		if _, err := compileRegex(term); err != nil {
+			return "", nil, err
+		}
+		query := "SELECT b.id, b.bookName, b.book, b.chapter, b.verse, b.text FROM bible b WHERE b.text REGEXP ?" + scope + " ORDER BY b.id" + page
+		return query, append(append([]any{term}, scopeParams...), pageParams...), nil
+	}
+
+	expr, err := ParseSearch(term, opts.Exact)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := EnsureSearchIndex(db); err != nil {
+		if opts.Rank || hasNear(expr) {
+			warnNoSearchIndex.Do(func() {
+				fmt.Fprintf(os.Stderr, "Warning: there's no full text search index (%v), so NEAR only checks that the words "+
+					"are in the same verse and --rank is ignored. See \"Building\" in the README\n", err)
+			})
+		}
+		where, params := expr.sql(likeText())
+		query := "SELECT id, bookName, book, chapter, verse, text FROM bible WHERE " + where + strings.ReplaceAll(scope, "b.id", "id") + " ORDER BY id" + page
+		return query, append(append(params, scopeParams...), pageParams...), nil
+	}
+
+	order := "b.id"
+	if opts.Rank {
+		order = "bible_fts.rank"
+	}
+
+	query := "SELECT b.id, b.bookName, b.book, b.chapter, b.verse, b.text FROM bible_fts JOIN bible b ON b.id = bible_fts.rowid " +
+		"WHERE bible_fts MATCH ?" + scope + " ORDER BY " + order + page
+
+	return query, append(append([]any{expr.fts()}, scopeParams...), pageParams...), nil
+}
+
+
+// Search gives back every verse that matches the search (or the page of them, with opts.Limit and opts.Offset)
+func Search(db *sql.DB, term string, opts SearchOptions) ([]Bible, error) {
+	query, params, err := BuildSearchQuery(db, term, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	verses := []Bible{}
+	for rows.Next() {
+		var verse Bible
+		if err := rows.Scan(&verse.ID, &verse.BookName, &verse.Book, &verse.Chapter, &verse.Verse, &verse.Text); err != nil {
+			return nil, err
+		}
+		verses = append(verses, verse)
+	}
+
+	return verses, rows.Err()
+}
+
+
+// CountSearchResults gives back how many verses matched in each book, in bible order.
+// It counts everything, so --limit and --offset don't change it.
+func CountSearchResults(db *sql.DB, term string, opts SearchOptions) ([]BookCount, error) {
+	opts.Limit = 0
+	opts.Offset = 0
+	query, params, err := BuildSearchQuery(db, term, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT bookName, COUNT(*) FROM ("+query+") GROUP BY bookName ORDER BY MIN(id)", params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []BookCount
+	for rows.Next() {
+		var count BookCount
+		if err := rows.Scan(&count.BookName, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+
+// Splits text into words (letters and numbers) so the matches can be found for highlighting
+var searchWords = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+
+// SearchHighlighter gives back a function that colors the matches for a search in a verse. If colors are off it does nothing.
+func SearchHighlighter(term string, opts SearchOptions) func(string) string {
+	if !ColorEnabled() {
+		return func(text string) string { return text }
+	}
+
+	// For a regular expression, whatever it matched is what gets colored
+	if opts.Regex {
+		re, err := compileRegex(term)
+		if err != nil {
+			return func(text string) string { return text }
+		}
+		return func(text string) string {
+			return re.ReplaceAllStringFunc(text, func(match string) string {
+				return colorize(match, colorHighlight)
+			})
+		}
+	}
+
+	expr, err := ParseSearch(term, opts.Exact)
+	if err != nil {
+		return func(text string) string { return text }
+	}
+	return func(text string) string {
+		return HighlightMatches(text, expr)
+	}
+}
+
+
+// HighlightMatches colors every word in the text that the search would have matched. If colors are off it does nothing.
+func HighlightMatches(text string, expr SearchExpr) string {
+	if !ColorEnabled() {
+		return text
+	}
+
+	terms := expr.terms()
+	return searchWords.ReplaceAllStringFunc(text, func(word string) string {
+		lower := strings.ToLower(word)
+		for _, t := range terms {
+			search := strings.ToLower(t.word)
+			if lower == search || (t.prefix && strings.HasPrefix(lower, search)) {
+				return colorize(word, colorHighlight)
+			}
+		}
+		return word
+	})
+}
+
+
+// Turns --in into " AND (b.id BETWEEN ? AND ? OR ...)" to stick on the end of the WHERE. Gives back "" if there's no scope
+func scopeSql(db *sql.DB, in string) (string, []any, error) {
+	if strings.TrimSpace(in) == "" {
+		return "", nil, nil
+	}
+
+	ranges, err := ParseScope(in)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var parts []string
+	var params []any
+	for _, r := range ranges {
+		start, end, err := GetIdRange(db, r)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, "b.id BETWEEN ? AND ?")
+		params = append(params, start, end)
+	}
+
+	return " AND (" + strings.Join(parts, " OR ") + ")", params, nil
+}
+
+
+// Puts double quotes around something for fts5. Any double quotes inside get doubled up
+func quoteFts(s string) string {
+	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+}
+
+
+// Punctuation gets turned into spaces in the LIKE search, so words next to a comma or at the end of a sentence still match
+var likePunctuation = []string{",", ".", ";", ":", "!", "?", "(", ")", "'"}
+
+
+// The SQL for the verse text that the LIKE search uses. The punctuation is taken out and there's a space on each end,
+// so "% love %" matches love as a whole word even at the start or end of a verse
+func likeText() string {
+	text := "text"
+	for _, p := range likePunctuation {
+		text = fmt.Sprintf("replace(%s, '%s', ' ')", text, strings.ReplaceAll(p, "'", "''"))
+	}
+	return "(' ' || " + text + " || ' ')"
+}
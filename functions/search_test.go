@@ -0,0 +1,145 @@
+package functions
+
+import (
+	"reflect"
+	"testing"
+	"database/sql"
+)
+
+
+// The test bible with a few real verses to search for
+func newSearchTestDb(t *testing.T) *sql.DB {
+	t.Helper()
+	db := newTestBibleDb(t)
+	db.Exec("UPDATE bible SET text = 'And God said, Let there be light: and there was light.' WHERE id = 3")
+	db.Exec("UPDATE bible SET text = 'For God so loved the world, that he gave his only begotten Son' WHERE id = 148")
+	db.Exec("UPDATE bible SET text = 'And he that loveth not knoweth not God; for God is love.' WHERE id = 5")
+	return db
+}
+
+
+// Runs the search and gives back the ids. This works with or without fts5
+func searchIds(t *testing.T, db *sql.DB, term string, opts SearchOptions) []int {
+	t.Helper()
+	query, params, err := BuildSearchQuery(db, term, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var verse Bible
+		rows.Scan(&verse.ID, &verse.BookName, &verse.Book, &verse.Chapter, &verse.Verse, &verse.Text)
+		ids = append(ids, verse.ID)
+	}
+	return ids
+}
+
+
+func TestBuildSearchQuery(t *testing.T) {
+	tests := []struct {
+		term	string
+		opts	SearchOptions
+		want	[]int
+	}{
+		{"light", SearchOptions{}, []int{3}},
+		{"love", SearchOptions{}, []int{5, 148}},
+		{"love", SearchOptions{Exact: true}, []int{5}},
+		{"God so loved", SearchOptions{}, []int{148}},
+		{"light OR loved", SearchOptions{}, []int{3, 148}},
+		{"God NOT love", SearchOptions{}, []int{3}},
+		{"love", SearchOptions{In: "John"}, []int{148}},
+		{"love", SearchOptions{In: "Genesis 1:1-5; Jude"}, []int{5}},
+		{"light OR loved", SearchOptions{In: "John 3:16"}, []int{148}},
+		{"nothing like this", SearchOptions{}, nil},
+	}
+
+	for _, test := range tests {
+		if got := searchIds(t, newSearchTestDb(t), test.term, test.opts); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("searching %q %+v gave %v, want %v", test.term, test.opts, got, test.want)
+		}
+	}
+
+	if _, _, err := BuildSearchQuery(newTestBibleDb(t), "  ", SearchOptions{}); err == nil {
+		t.Errorf("An empty search should give an error")
+	}
+	if _, _, err := BuildSearchQuery(newTestBibleDb(t), "love", SearchOptions{In: "Nowhere"}); err == nil {
+		t.Errorf("Searching in a book that isn't there should give an error")
+	}
+}
+
+
+func TestRegexSearch(t *testing.T) {
+	db := newSearchTestDb(t)
+	tests := []struct {
+		pattern	string
+		opts	SearchOptions
+		want	[]int
+	}{
+		{`\blight\b`, SearchOptions{}, []int{3}},
+		{`\blov(e|ed)\b`, SearchOptions{}, []int{5, 148}},
+		// Case doesn't matter unless the pattern says so
+		{`GOD SO`, SearchOptions{}, []int{148}},
+		{`(?-i)GOD SO`, SearchOptions{}, nil},
+		{`\blov(e|ed)\b`, SearchOptions{In: "John"}, []int{148}},
+		{`God`, SearchOptions{Limit: 1, Offset: 1}, []int{5}},
+	}
+
+	for _, test := range tests {
+		test.opts.Regex = true
+		if got := searchIds(t, db, test.pattern, test.opts); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("searching /%s/ %+v gave %v, want %v", test.pattern, test.opts, got, test.want)
+		}
+	}
+
+	if _, _, err := BuildSearchQuery(db, "lov(e", SearchOptions{Regex: true}); err == nil {
+		t.Errorf("A broken regular expression should give an error")
+	}
+}
+
+
+func TestSearchPages(t *testing.T) {
+	db := newSearchTestDb(t)
+	tests := []struct {
+		limit	int
+		offset	int
+		want	[]int
+	}{
+		{0, 0, []int{3, 5, 148}},
+		{2, 0, []int{3, 5}},
+		{2, 2, []int{148}},
+		{0, 1, []int{5, 148}},
+		{1, 5, nil},
+	}
+
+	for _, test := range tests {
+		opts := SearchOptions{Limit: test.limit, Offset: test.offset}
+		if got := searchIds(t, db, "God", opts); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("searching God with --limit %d --offset %d gave %v, want %v", test.limit, test.offset, got, test.want)
+		}
+	}
+}
+
+
+func TestCountSearchResults(t *testing.T) {
+	db := newSearchTestDb(t)
+
+	// The limit doesn't change the counts
+	counts, err := CountSearchResults(db, "God", SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []BookCount{{"Genesis", 2}, {"John", 1}}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("CountSearchResults(God) = %+v, want %+v", counts, want)
+	}
+
+	counts, err = CountSearchResults(db, "nothing", SearchOptions{})
+	if err != nil || len(counts) != 0 {
+		t.Errorf("CountSearchResults(nothing) = %+v, %v, want no counts", counts, err)
+	}
+}
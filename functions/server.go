@@ -0,0 +1,214 @@
+package functions
+
+import (
+	"fmt"
+	"errors"
+	"strconv"
+	"strings"
+	"net/http"
+	"database/sql"
+	"encoding/json"
+)
+
+
+// Serve starts the http server (bible serve --addr :8080). It doesn't return unless the server stops.
+func Serve(db *sql.DB, addr string) error {
+	fmt.Printf("Serving the bible on %s\n", addr)
+	return http.ListenAndServe(addr, NewServer(db))
+}
+
+
+// NewServer gives back the http handler with all the endpoints on it. Everything comes back as json,
+// and errors look like {"error": "..."} with a 400 (bad request) or 404 (can't find it) status.
+//
+//	GET /passage?ref=John+3:16-18
+//	GET /search?q=love&in=NT&exact=true&rank=true&regex=true&limit=10&offset=0
+//	GET /random
+//	GET /books
+//	GET /books/{book}/chapters
+//	GET /favorites
+func NewServer(db *sql.DB) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /passage", func(w http.ResponseWriter, r *http.Request) {
+		refString := r.URL.Query().Get("ref")
+		if refString == "" {
+			writeError(w, http.StatusBadRequest, "Missing ref, ie /passage?ref=John+3:16")
+			return
+		}
+
+		ref, err := ParseReference(refString)
+		if err != nil {
+			writeLookupError(w, err)
+			return
+		}
+
+		verses := []Bible{}
+		for _, vr := range ref.Ranges {
+			rangeVerses, err := GetVersesInRange(db, vr)
+			if err != nil {
+				writeLookupError(w, err)
+				return
+			}
+			verses = append(verses, rangeVerses...)
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"reference": ref.String(),
+			"verses": verses,
+		})
+	})
+
+	mux.HandleFunc("GET /search", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		term := query.Get("q")
+		if term == "" {
+			writeError(w, http.StatusBadRequest, "Missing q, ie /search?q=love")
+			return
+		}
+
+		opts := SearchOptions{
+			Exact: query.Get("exact") == "true",
+			Rank: query.Get("rank") == "true",
+			Regex: query.Get("regex") == "true",
+			In: query.Get("in"),
+		}
+		var err error
+		if opts.Limit, err = intParam(query.Get("limit")); err != nil {
+			writeError(w, http.StatusBadRequest, "limit "+err.Error())
+			return
+		}
+		if opts.Offset, err = intParam(query.Get("offset")); err != nil {
+			writeError(w, http.StatusBadRequest, "offset "+err.Error())
+			return
+		}
+
+		counts, err := CountSearchResults(db, term, opts)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		verses, err := Search(db, term, opts)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		total := 0
+		for _, count := range counts {
+			total += count.Count
+		}
+		if counts == nil {
+			counts = []BookCount{}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"query": term,
+			"total": total,
+			"books": counts,
+			"verses": verses,
+		})
+	})
+
+	mux.HandleFunc("GET /random", func(w http.ResponseWriter, r *http.Request) {
+		verse, err := LookupRandomVerse(db)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, verse)
+	})
+
+	mux.HandleFunc("GET /books", func(w http.ResponseWriter, r *http.Request) {
+		books, err := ListBooks(db)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, books)
+	})
+
+	mux.HandleFunc("GET /books/{book}/chapters", func(w http.ResponseWriter, r *http.Request) {
+		book, err := ResolveBook(r.PathValue("book"))
+		if err != nil {
+			writeLookupError(w, err)
+			return
+		}
+
+		chapters, err := ListChapters(db, book)
+		if err != nil {
+			writeLookupError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"book": book,
+			"chapters": chapters,
+		})
+	})
+
+	mux.HandleFunc("GET /favorites", func(w http.ResponseWriter, r *http.Request) {
+		data, err := LoadSaveData()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		verses := []Bible{}
+		for _, id := range data.Favorites {
+			verse, err := LookupVerseId(db, id)
+			// A favorite from another translation might not be in this one
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			verses = append(verses, verse)
+		}
+		writeJSON(w, http.StatusOK, verses)
+	})
+
+	// Anything else is a 404, but in json like everything else
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "Not found: "+r.URL.Path)
+	})
+
+	return mux
+}
+
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+
+// Things that don't exist are a 404, anything else wrong with the request is a 400
+func writeLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeError(w, http.StatusBadRequest, err.Error())
+}
+
+
+// Reads a number from the query string. Empty is 0
+func intParam(value string) (int, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("must be a number that isn't negative")
+	}
+	return n, nil
+}
@@ -0,0 +1,140 @@
+package functions
+
+import (
+	"reflect"
+	"testing"
+	"net/http"
+	"encoding/json"
+	"net/http/httptest"
+)
+
+
+// Makes a request to the server and decodes the json that comes back into v
+func getJSON(t *testing.T, server http.Handler, url string, v any) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("%s came back as %q, not json", url, contentType)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s gave back %q: %v", url, w.Body.String(), err)
+	}
+	return w.Code
+}
+
+
+func TestServePassage(t *testing.T) {
+	server := NewServer(newTestBibleDb(t))
+
+	var passage struct {
+		Reference	string	`json:"reference"`
+		Verses		[]Bible	`json:"verses"`
+	}
+	if status := getJSON(t, server, "/passage?ref=John+3:16-17", &passage); status != http.StatusOK {
+		t.Fatalf("/passage gave a %d", status)
+	}
+	want := []Bible{
+		{ID: 148, BookName: "John", Book: 43, Chapter: 3, Verse: 16, Text: "John 3:16 text"},
+		{ID: 149, BookName: "John", Book: 43, Chapter: 3, Verse: 17, Text: "John 3:17 text"},
+	}
+	if passage.Reference != "John 3:16-17" || !reflect.DeepEqual(passage.Verses, want) {
+		t.Errorf("/passage?ref=John+3:16-17 = %+v", passage)
+	}
+}
+
+
+func TestServeSearch(t *testing.T) {
+	server := NewServer(newSearchTestDb(t))
+
+	var results struct {
+		Query	string		`json:"query"`
+		Total	int			`json:"total"`
+		Books	[]BookCount	`json:"books"`
+		Verses	[]Bible		`json:"verses"`
+	}
+	if status := getJSON(t, server, "/search?q=God&limit=1&offset=1", &results); status != http.StatusOK {
+		t.Fatalf("/search gave a %d", status)
+	}
+	// The total and counts are for everything, the verses are just the page
+	if results.Total != 3 || len(results.Books) != 2 || len(results.Verses) != 1 || results.Verses[0].ID != 5 {
+		t.Errorf("/search?q=God&limit=1&offset=1 = %+v", results)
+	}
+}
+
+
+func TestServeBooks(t *testing.T) {
+	server := NewServer(newTestBibleDb(t))
+
+	var books []BookInfo
+	getJSON(t, server, "/books", &books)
+	want := []BookInfo{{"Genesis", 1, 2}, {"John", 43, 3}, {"Jude", 65, 1}}
+	if !reflect.DeepEqual(books, want) {
+		t.Errorf("/books = %+v, want %+v", books, want)
+	}
+
+	var chapters struct {
+		Book		string			`json:"book"`
+		Chapters	[]ChapterInfo	`json:"chapters"`
+	}
+	getJSON(t, server, "/books/jn/chapters", &chapters)
+	if chapters.Book != "John" || !reflect.DeepEqual(chapters.Chapters, []ChapterInfo{{1, 51}, {2, 25}, {3, 36}}) {
+		t.Errorf("/books/jn/chapters = %+v", chapters)
+	}
+}
+
+
+func TestServeFavorites(t *testing.T) {
+	useTempUserDb(t)
+	server := NewServer(newTestBibleDb(t))
+
+	// No data file yet
+	var verses []Bible
+	if status := getJSON(t, server, "/favorites", &verses); status != http.StatusOK || len(verses) != 0 {
+		t.Errorf("/favorites with no data file = %d %+v", status, verses)
+	}
+
+	err := UpdateUserData(func(ut *UserTx) error {
+		if err := ut.AddFavorite(148); err != nil {
+			return err
+		}
+		return ut.AddFavorite(1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	getJSON(t, server, "/favorites", &verses)
+	if len(verses) != 2 || verses[0].ID != 1 || verses[1].ID != 148 {
+		t.Errorf("/favorites = %+v", verses)
+	}
+}
+
+
+func TestServeErrors(t *testing.T) {
+	server := NewServer(newTestBibleDb(t))
+
+	tests := []struct {
+		url		string
+		status	int
+	}{
+		{"/passage", http.StatusBadRequest},
+		{"/passage?ref=Jhon+3:16", http.StatusNotFound},
+		{"/passage?ref=John+3:16-", http.StatusBadRequest},
+		{"/passage?ref=Romans+1:1", http.StatusNotFound},
+		{"/search", http.StatusBadRequest},
+		{"/search?q=love&limit=x", http.StatusBadRequest},
+		{"/search?q=love&offset=-1", http.StatusBadRequest},
+		{"/search?q=love+AND", http.StatusBadRequest},
+		{"/books/Jhon/chapters", http.StatusNotFound},
+		{"/books/Romans/chapters", http.StatusNotFound},
+		{"/nothing", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		var body map[string]string
+		status := getJSON(t, server, test.url, &body)
+		if status != test.status || body["error"] == "" {
+			t.Errorf("%s = %d %v, want a %d with an error", test.url, status, body, test.status)
+		}
+	}
+}
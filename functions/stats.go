@@ -0,0 +1,317 @@
+package functions
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"strconv"
+	"strings"
+	"database/sql"
+)
+
+
+// Everything "bible stats" reports. The reading comes from the history (history.go) and the reading plan progress (plans.go)
+type ReadingStats struct {
+	VersesRead		int					`json:"versesRead"`
+	TotalVerses		int					`json:"totalVerses"`
+	Percent			float64				`json:"percent"`
+	Testaments		[]Coverage			`json:"testaments"`
+	Books			[]Coverage			`json:"books"`
+	DaysRead		int					`json:"daysRead"`
+	CurrentStreak	int					`json:"currentStreak"`	// Days in a row up to today (or yesterday, if today hasn't been read yet)
+	LongestStreak	int					`json:"longestStreak"`
+	VersesPerDay	float64				`json:"versesPerDay"`	// On the days that something was read
+	RecentDays		[]DayCount			`json:"recentDays"`		// The last statsDays days, oldest first
+	UnreadChapters	map[string][]int	`json:"unreadChapters"`	// Chapters that have never been read, by book
+}
+
+
+// How much of a book (or testament) has been read
+type Coverage struct {
+	Name		string		`json:"name"`
+	Read		int			`json:"read"`
+	Total		int			`json:"total"`
+	Percent		float64		`json:"percent"`
+}
+
+
+// How many different verses were read on a day
+type DayCount struct {
+	Date	string	`json:"date"`
+	Verses	int		`json:"verses"`
+}
+
+
+// How many days back the chart of verses per day goes
+const statsDays = 14
+
+// How wide the bars are
+const barWidth = 20
+
+
+// GetReadingStats works out how much has been read. The totals for each book and chapter come from
+// GetAllChaptersInBook and GetAllVersesInChapter, so they match whatever translation db is.
+func GetReadingStats(db *sql.DB) (ReadingStats, error) {
+	stats := ReadingStats{UnreadChapters: make(map[string][]int)}
+
+	read, perDay, err := versesRead(db)
+	if err != nil {
+		return stats, err
+	}
+
+	books, err := ListBooks(db)
+	if err != nil {
+		return stats, err
+	}
+
+	oldTestament := Coverage{Name: "Old Testament"}
+	newTestament := Coverage{Name: "New Testament"}
+	for _, book := range books {
+		coverage := Coverage{Name: book.Name}
+
+		chapters := GetAllChaptersInBook(db, book.Name)
+		for chapter := 1; chapter <= chapters; chapter++ {
+			total := GetAllVersesInChapter(db, book.Name, strconv.Itoa(chapter))
+
+			// The chapter's own ids, so it doesn't matter if a translation skips some between chapters
+			first, last, err := chapterIds(db, book.Name, chapter)
+			if err != nil {
+				return stats, err
+			}
+			chapterRead := 0
+			for id := first; id <= last; id++ {
+				if read[id] {
+					chapterRead++
+				}
+			}
+			// The history has ranges of ids, so if there are gaps inside the chapter those got counted too
+			chapterRead = min(chapterRead, total)
+			if chapterRead == 0 {
+				stats.UnreadChapters[book.Name] = append(stats.UnreadChapters[book.Name], chapter)
+			}
+
+			coverage.Read += chapterRead
+			coverage.Total += total
+		}
+		coverage.Percent = percent(coverage.Read, coverage.Total)
+		stats.Books = append(stats.Books, coverage)
+
+		testament := &oldTestament
+		if bookIndex(book.Name) >= bookIndex("Matthew") {
+			testament = &newTestament
+		}
+		testament.Read += coverage.Read
+		testament.Total += coverage.Total
+
+		stats.VersesRead += coverage.Read
+		stats.TotalVerses += coverage.Total
+	}
+
+	oldTestament.Percent = percent(oldTestament.Read, oldTestament.Total)
+	newTestament.Percent = percent(newTestament.Read, newTestament.Total)
+	stats.Testaments = []Coverage{oldTestament, newTestament}
+	stats.Percent = percent(stats.VersesRead, stats.TotalVerses)
+
+	// Days, streaks and verses per day
+	var days []string
+	totalPerDay := 0
+	for day, verses := range perDay {
+		days = append(days, day)
+		totalPerDay += len(verses)
+	}
+	sort.Strings(days)
+	stats.DaysRead = len(days)
+	if len(days) > 0 {
+		stats.VersesPerDay = float64(totalPerDay) / float64(len(days))
+	}
+	stats.CurrentStreak, stats.LongestStreak = streaks(days, time.Now())
+
+	today := time.Now()
+	for i := statsDays - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i).Format(planDateFormat)
+		stats.RecentDays = append(stats.RecentDays, DayCount{Date: day, Verses: len(perDay[day])})
+	}
+
+	return stats, nil
+}
+
+
+// Every verse id that has been read, and which ones were read on each day (ie "2026-10-18")
+func versesRead(db *sql.DB) (map[int]bool, map[string]map[int]bool, error) {
+	read := make(map[int]bool)
+	perDay := make(map[string]map[int]bool)
+	add := func(day string, start int, end int) {
+		if perDay[day] == nil {
+			perDay[day] = make(map[int]bool)
+		}
+		for id := start; id <= end; id++ {
+			read[id] = true
+			perDay[day][id] = true
+		}
+	}
+
+	history, err := LoadHistory(time.Time{}, time.Time{}, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, entry := range history {
+		add(entry.Viewed.Format(planDateFormat), entry.Start, entry.End)
+	}
+
+	// Days marked done in the reading plan count too, even if they were read in a paper bible
+	userDb, err := OpenUserDb()
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := userDb.Query(`SELECT date, start_id, end_id FROM plan_progress
+		JOIN plan_progress_ranges ON plan_progress_ranges.day = plan_progress.day`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day string
+		var start, end int
+		if err := rows.Scan(&day, &start, &end); err != nil {
+			return nil, nil, err
+		}
+		add(day, start, end)
+	}
+
+	return read, perDay, rows.Err()
+}
+
+
+// Works out the current and longest streaks (days in a row) from the days that were read, sorted.
+// The current one still counts if the last day was yesterday, because there's still time to read today
+func streaks(days []string, now time.Time) (int, int) {
+	current, longest, run := 0, 0, 0
+	var last time.Time
+	for _, day := range days {
+		date, err := time.ParseInLocation(planDateFormat, day, time.Local)
+		if err != nil {
+			continue
+		}
+		if run > 0 && date.Sub(last).Hours() < 36 {
+			run++
+		} else {
+			run = 1
+		}
+		last = date
+		longest = max(longest, run)
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if !last.IsZero() && today.Sub(last).Hours() < 36 {
+		current = run
+	}
+	return current, longest
+}
+
+
+func percent(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+
+// A bar for the charts, filled in as much as the fraction (0 to 1)
+func bar(fraction float64, width int) string {
+	full, empty := "█", "░"
+	if !unicodeTerminal() {
+		full, empty = "#", "-"
+	}
+	filled := int(fraction*float64(width) + 0.5)
+	filled = min(max(filled, 0), width)
+	return colorize(strings.Repeat(full, filled), highlightColors["green"]) + strings.Repeat(empty, width-filled)
+}
+
+
+// PrintStats prints the stats with bar charts
+func PrintStats(stats ReadingStats) {
+	if stats.VersesRead == 0 {
+		fmt.Println("Nothing read yet. Everything you read (bible John 3, -i, --tui, reading plans) counts towards the stats")
+		return
+	}
+
+	fmt.Printf("Read %d of %d verses (%.1f%%)\n\n", stats.VersesRead, stats.TotalVerses, stats.Percent)
+
+	for _, testament := range stats.Testaments {
+		printCoverage(testament)
+	}
+	fmt.Println()
+
+	var notStarted []string
+	for _, book := range stats.Books {
+		if book.Read == 0 {
+			notStarted = append(notStarted, book.Name)
+			continue
+		}
+		printCoverage(book)
+	}
+	if len(notStarted) > 0 {
+		fmt.Println()
+		WordWrap(fmt.Sprintf("Not started (%d): %s", len(notStarted), strings.Join(notStarted, ", ")))
+	}
+
+	fmt.Println()
+	fmt.Printf("Streak: %d day%s (longest %d)\n", stats.CurrentStreak, plural(stats.CurrentStreak), stats.LongestStreak)
+	fmt.Printf("Read on %d day%s, %.0f verses a day\n", stats.DaysRead, plural(stats.DaysRead), stats.VersesPerDay)
+
+	// Verses per day for the last couple of weeks, scaled to the busiest day
+	most := 1
+	for _, day := range stats.RecentDays {
+		most = max(most, day.Verses)
+	}
+	fmt.Printf("\nThe last %d days:\n", statsDays)
+	for _, day := range stats.RecentDays {
+		date, _ := time.ParseInLocation(planDateFormat, day.Date, time.Local)
+		fmt.Printf("  %s  %s %d\n", date.Format("Mon Jan 02"), bar(float64(day.Verses)/float64(most), barWidth), day.Verses)
+	}
+
+	// Chapters never read, only for the books that have been started (the rest are all in "Not started")
+	var unread []string
+	for _, book := range stats.Books {
+		if chapters := stats.UnreadChapters[book.Name]; book.Read > 0 && len(chapters) > 0 {
+			unread = append(unread, book.Name+" "+chapterNumbersString(chapters))
+		}
+	}
+	if len(unread) > 0 {
+		fmt.Println()
+		WordWrap("Chapters never read: " + strings.Join(unread, "; "))
+	}
+}
+
+
+func printCoverage(c Coverage) {
+	fmt.Printf("  %-16s %s %5.1f%%  %d/%d\n", c.Name, bar(c.Percent/100, barWidth), c.Percent, c.Read, c.Total)
+}
+
+
+// Chapter numbers with the runs joined up, ie 1-3, 5, 7-9
+func chapterNumbersString(chapters []int) string {
+	var parts []string
+	for i := 0; i < len(chapters); {
+		j := i
+		for j+1 < len(chapters) && chapters[j+1] == chapters[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", chapters[i], chapters[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(chapters[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
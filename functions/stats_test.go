@@ -0,0 +1,114 @@
+package functions
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+
+func TestStreaks(t *testing.T) {
+	now := time.Date(2026, 10, 18, 20, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		days		[]string
+		current		int
+		longest		int
+	}{
+		{nil, 0, 0},
+		{[]string{"2026-10-18"}, 1, 1},
+		{[]string{"2026-10-16", "2026-10-17", "2026-10-18"}, 3, 3},
+		// Today hasn't been read yet, but there's still time
+		{[]string{"2026-10-16", "2026-10-17"}, 2, 2},
+		// Missed yesterday, so it's over
+		{[]string{"2026-10-15", "2026-10-16"}, 0, 2},
+		{[]string{"2026-10-01", "2026-10-02", "2026-10-03", "2026-10-04", "2026-10-17", "2026-10-18"}, 2, 4},
+		// Over the end of a month and daylight savings
+		{[]string{"2026-03-30", "2026-03-31", "2026-04-01", "2026-04-02", "2026-04-03"}, 0, 5},
+		{[]string{"2025-10-24", "2025-10-25", "2025-10-26", "2025-11-01", "2025-11-02"}, 0, 3},
+	}
+
+	for _, test := range tests {
+		current, longest := streaks(test.days, now)
+		if current != test.current || longest != test.longest {
+			t.Errorf("streaks(%q) = %d, %d, want %d, %d", test.days, current, longest, test.current, test.longest)
+		}
+	}
+}
+
+
+func TestChapterNumbersString(t *testing.T) {
+	tests := []struct {
+		chapters	[]int
+		want		string
+	}{
+		{nil, ""},
+		{[]int{4}, "4"},
+		{[]int{1, 2}, "1-2"},
+		{[]int{1, 2, 3, 5, 7, 8, 9}, "1-3, 5, 7-9"},
+		{[]int{2, 4, 6}, "2, 4, 6"},
+	}
+
+	for _, test := range tests {
+		if got := chapterNumbersString(test.chapters); got != test.want {
+			t.Errorf("chapterNumbersString(%v) = %q, want %q", test.chapters, got, test.want)
+		}
+	}
+}
+
+
+func TestGetReadingStats(t *testing.T) {
+	useTempUserDb(t)
+	db := newTestBibleDb(t)
+
+	// Some translations leave verses out, so John 2 ends at 2:24 (id 131) and John 3 still starts at 133
+	if _, err := db.Exec("DELETE FROM bible WHERE id = 132"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read John 3 today, and Genesis 1 and half of Genesis 2 for the plan yesterday
+	LogReading(db, 133, 168, "reference")
+	yesterday := time.Now().AddDate(0, 0, -1).Format(planDateFormat)
+	err := UpdateUserData(func(ut *UserTx) error {
+		if err := ut.StartPlan("test", yesterday); err != nil {
+			return err
+		}
+		return ut.MarkPlanDay(PlanDay{Day: 1, Date: yesterday, Ranges: [][2]int{{1, 31}, {32, 44}}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetReadingStats(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBooks := []Coverage{
+		{Name: "Genesis", Read: 44, Total: 56, Percent: percent(44, 56)},
+		{Name: "John", Read: 36, Total: 111, Percent: percent(36, 111)},
+		{Name: "Jude", Read: 0, Total: 25, Percent: 0},
+	}
+	if !reflect.DeepEqual(stats.Books, wantBooks) {
+		t.Errorf("Books = %+v, want %+v", stats.Books, wantBooks)
+	}
+	if stats.VersesRead != 80 || stats.TotalVerses != 192 {
+		t.Errorf("Read %d of %d verses, want 80 of 192", stats.VersesRead, stats.TotalVerses)
+	}
+	if stats.Testaments[0].Read != 44 || stats.Testaments[1].Read != 36 {
+		t.Errorf("Testaments = %+v, want 44 in the Old and 36 in the New", stats.Testaments)
+	}
+
+	wantUnread := map[string][]int{"John": {1, 2}, "Jude": {1}}
+	if !reflect.DeepEqual(stats.UnreadChapters, wantUnread) {
+		t.Errorf("UnreadChapters = %v, want %v", stats.UnreadChapters, wantUnread)
+	}
+
+	if stats.DaysRead != 2 || stats.CurrentStreak != 2 || stats.LongestStreak != 2 || stats.VersesPerDay != 40 {
+		t.Errorf("Days = %d, streaks = %d and %d, per day = %v, want 2, 2 and 2, 40",
+			stats.DaysRead, stats.CurrentStreak, stats.LongestStreak, stats.VersesPerDay)
+	}
+	if n := len(stats.RecentDays); n != statsDays || stats.RecentDays[n-1].Verses != 36 || stats.RecentDays[n-2].Verses != 44 {
+		t.Errorf("RecentDays = %+v, want 44 then 36 at the end", stats.RecentDays)
+	}
+}
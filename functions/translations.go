@@ -0,0 +1,132 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"sort"
+	"strings"
+	"path/filepath"
+	"database/sql"
+)
+
+
+// A translation of the bible. Every translation is a sqlite database with a "bible" table laid out the
+// same way as kjv.db (id, bookName, book, chapter, verse, text). tool/json_to_sqlite.go can make one from json.
+type Translation struct {
+	Name		string
+	Description	string
+	Path		string
+	Embedded	bool
+}
+
+
+// All the translations that have been registered. The embedded one(s) get registered by main,
+// anything in the translations folder gets picked up by Translations()
+var registeredTranslations []Translation
+
+
+// RegisterTranslation adds a translation to the list. If there is already one with the same name, it gets replaced.
+func RegisterTranslation(t Translation) {
+	for i, existing := range registeredTranslations {
+		if strings.EqualFold(existing.Name, t.Name) {
+			registeredTranslations[i] = t
+			return
+		}
+	}
+	registeredTranslations = append(registeredTranslations, t)
+}
+
+
+// GetTranslationsDir gives back the folder where user installed translations go (~/.local/share/bible/translations)
+// To install one, just drop the .db file in there. The name of the file is the name of the translation (ie asv.db is ASV)
+func GetTranslationsDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".local", "share", "bible", "translations")
+}
+
+
+// Translations gives back all the translations that are available. The registered (embedded) ones first,
+// then the installed ones sorted by name.
+func Translations() []Translation {
+	all := append([]Translation{}, registeredTranslations...)
+
+	files, err := filepath.Glob(filepath.Join(GetTranslationsDir(), "*.db"))
+	if err != nil {
+		return all
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := strings.ToUpper(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+
+		// Registered ones win, so you can't accidentally hide the embedded KJV
+		if containsTranslation(all, name) {
+			continue
+		}
+
+		all = append(all, Translation{
+			Name: name,
+			Description: "Installed (" + file + ")",
+			Path: file,
+		})
+	}
+
+	return all
+}
+
+
+// FindTranslation looks up a translation by name. Case doesn't matter, so "web" finds "WEB"
+func FindTranslation(name string) (Translation, error) {
+	var names []string
+	for _, t := range Translations() {
+		if strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+
+	return Translation{}, fmt.Errorf("Can't find translation \"%s\". Available translations: %s", name, strings.Join(names, ", "))
+}
+
+
+// OpenTranslation opens the database for a translation, and makes sure it actually has the bible table in it
+func OpenTranslation(name string) (*sql.DB, error) {
+	t, err := FindTranslation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The embedded one is a copy in the cache folder with its checksum in the name (see CacheDatabase), so nothing
+	// is allowed to change it. Its search index gets built into kjv.db by tool/json_to_sqlite.go
+	path := t.Path
+	if t.Embedded {
+		path = "file:" + t.Path + "?mode=ro"
+	}
+
+	db, err := OpenDatabase(path)
+	if err != nil {
+		return nil, fmt.Errorf("Translation %s: %v", t.Name, err)
+	}
+
+	return db, nil
+}
+
+
+// Prints all the available translations (bible -t list)
+func ListTranslations() {
+	for _, t := range Translations() {
+		fmt.Printf("%-8s %s\n", t.Name, t.Description)
+	}
+	fmt.Println()
+	WordWrap("To install another translation, put its .db file in " + GetTranslationsDir())
+}
+
+
+func containsTranslation(translations []Translation, name string) bool {
+	for _, t := range translations {
+		if strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
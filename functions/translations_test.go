@@ -0,0 +1,98 @@
+package functions
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"path/filepath"
+)
+
+
+// Points the translations folder at an empty one, with only kjv registered (like main does), and gives back the folder
+func useTempTranslations(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir := GetTranslationsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	old := registeredTranslations
+	t.Cleanup(func() { registeredTranslations = old })
+	registeredTranslations = nil
+	RegisterTranslation(Translation{Name: "KJV", Description: "built in", Path: filepath.Join(t.TempDir(), "kjv.db"), Embedded: true})
+	writeTestBible(t, registeredTranslations[0].Path)
+	return dir
+}
+
+
+func TestTranslations(t *testing.T) {
+	dir := useTempTranslations(t)
+	writeTestBible(t, filepath.Join(dir, "web.db"))
+	writeTestBible(t, filepath.Join(dir, "asv.db"))
+	// Can't hide the built in one
+	os.WriteFile(filepath.Join(dir, "kjv.db"), []byte("not a bible"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a translation"), 0644)
+
+	var names []string
+	for _, translation := range Translations() {
+		names = append(names, translation.Name)
+	}
+	if want := []string{"KJV", "ASV", "WEB"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Translations() = %v, want %v", names, want)
+	}
+
+	translation, err := FindTranslation("web")
+	if err != nil || translation.Path != filepath.Join(dir, "web.db") {
+		t.Errorf("FindTranslation(\"web\") = %+v, %v, want web.db", translation, err)
+	}
+	if kjv, err := FindTranslation("KJV"); err != nil || !kjv.Embedded {
+		t.Errorf("FindTranslation(\"KJV\") = %+v, %v, want the built in one", kjv, err)
+	}
+	if _, err := FindTranslation("NIV"); err == nil {
+		t.Errorf("FindTranslation(\"NIV\") should give an error")
+	}
+
+	// Registering one with the same name replaces it
+	RegisterTranslation(Translation{Name: "kjv", Path: "other.db"})
+	if all := Translations(); len(all) != 3 || all[0].Path != "other.db" {
+		t.Errorf("After registering kjv again, Translations() = %+v", all)
+	}
+}
+
+
+func TestOpenTranslation(t *testing.T) {
+	dir := useTempTranslations(t)
+	writeTestBible(t, filepath.Join(dir, "web.db"))
+	os.WriteFile(filepath.Join(dir, "broken.db"), []byte("not a bible"), 0644)
+
+	db, err := OpenTranslation("WEB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text string
+	db.QueryRow("SELECT text FROM bible WHERE id = 57").Scan(&text)
+	db.Close()
+	if text != "John 1:1 text" {
+		t.Errorf("WEB John 1:1 = %q", text)
+	}
+
+	// The embedded one is the cached copy, so it can't be changed
+	db, err = OpenTranslation("KJV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("UPDATE bible SET text = 'changed' WHERE id = 1")
+	db.Close()
+	if err == nil {
+		t.Errorf("the embedded translation should be opened read only")
+	}
+
+	for _, name := range []string{"broken", "NIV"} {
+		if db, err := OpenTranslation(name); err == nil {
+			db.Close()
+			t.Errorf("OpenTranslation(%q) should give an error", name)
+		}
+	}
+}
+
@@ -0,0 +1,587 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"database/sql"
+	"golang.org/x/term"
+)
+
+
+// Escape codes for taking over the whole terminal. The alternate screen is what less and vim use,
+// so when the reader quits everything that was on the terminal before comes back.
+const (
+	enterAltScreen	= "\033[?1049h"
+	leaveAltScreen	= "\033[?1049l"
+	hideCursor		= "\033[?25l"
+	showCursor		= "\033[?25h"
+	clearLine		= "\033[2K"
+	cursorHome		= "\033[H"
+	reverseVideo	= "\033[7m"
+	boldText		= "\033[1m"
+)
+
+
+// The help that '?' shows in the full screen reader
+var readerHelp = []string{
+	"Full screen reader",
+	"",
+	"    j / down ......... scroll down a line",
+	"    k / up ........... scroll up a line",
+	"    space / PgDn ..... scroll down a page",
+	"    PgUp ............. scroll up a page",
+	"    g / G ............ top / bottom of the chapter",
+	"    n / p ............ next / previous verse",
+	"    ] / [ ............ next / previous chapter",
+	"    r ................ random verse",
+	"    f ................ favorite (on/off)",
+	"    m ................ highlight (mark) in a category",
+	"    a ................ add or edit a note on this verse",
+	"    : ................ type a command:",
+	"                         :John 3:16   go to a verse",
+	"                         :bookmark    save a bookmark here (:bookmark name for a named one)",
+	"                         :go name     go to a bookmark",
+	"                         :random      random verse",
+	"                         :q           quit",
+	"    q ................ quit",
+	"",
+	"Press any key to go back",
+}
+
+
+// One line on the screen. id is the verse it belongs to, or 0 for the heading and blank lines
+type readerLine struct {
+	text	string
+	id		int
+}
+
+
+// Everything the full screen reader needs to keep track of
+type reader struct {
+	db			*sql.DB
+	id			int				// The selected verse
+	verses		[]Bible			// Every verse in the chapter being shown
+	lines		[]readerLine	// The chapter, wrapped to fit the screen
+	top			int				// The line at the top of the screen
+	width		int
+	height		int
+	message		string			// Shown in the status bar until the next key
+	typing		bool			// True after ':' until enter or escape
+	command		[]rune			// What has been typed after ':'
+	help		bool			// Showing the help instead of the chapter
+	suspendKey	string			// Set by m and a, which need the normal terminal back for a bit
+}
+
+
+// FullScreenReader shows a whole chapter at a time, and takes over the terminal until you quit (bible --tui, or 't' in -i).
+// It starts at startId, and gives back the verse that was selected when it quit so interactive mode can carry on from there.
+func FullScreenReader(db *sql.DB, startId int) (int, error) {
+	inFd := int(os.Stdin.Fd())
+	if !term.IsTerminal(inFd) || !IsTerminal() {
+		return startId, errors.New("The full screen reader only works in a terminal")
+	}
+
+	r := &reader{db: db}
+	if err := r.goTo(startId); err != nil {
+		return startId, err
+	}
+
+	oldState, err := term.MakeRaw(inFd)
+	if err != nil {
+		return startId, err
+	}
+	fmt.Print(enterAltScreen + hideCursor)
+	defer func() {
+		fmt.Print(showCursor + leaveAltScreen)
+		term.Restore(inFd, oldState)
+	}()
+
+	buf := make([]byte, 64)
+	for {
+		r.draw()
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return r.id, nil
+		}
+
+		for _, key := range splitKeys(string(buf[:n])) {
+			if r.handleKey(key) {
+				return r.id, nil
+			}
+			if r.suspendKey != "" {
+				r.suspend(inFd, &oldState, r.suspendKey)
+				r.suspendKey = ""
+			}
+		}
+	}
+}
+
+
+// Moves to a verse, loading its chapter if it isn't the one already showing
+func (r *reader) goTo(id int) error {
+	if len(r.verses) > 0 && id >= r.verses[0].ID && id <= r.verses[len(r.verses)-1].ID {
+		r.id = id
+		r.scrollToSelected()
+		return nil
+	}
+
+	verse, err := LookupVerseId(r.db, id)
+	if err != nil {
+		return err
+	}
+	verses, err := GetVersesInRange(r.db, VerseRange{Book: verse.BookName, Chapter: verse.Chapter, EndChapter: verse.Chapter})
+	if err != nil {
+		return err
+	}
+
+	r.id = id
+	r.verses = verses
+	r.top = 0
+	r.layout()
+	r.scrollToSelected()
+
+	// A new chapter, so it goes in the history
+	LogReading(r.db, verses[0].ID, verses[len(verses)-1].ID, "reader")
+	return nil
+}
+
+
+// Wraps the chapter to the width of the screen. The verse numbers hang out on the left so the text lines up
+func (r *reader) layout() {
+	r.width, r.height = readerSize()
+	r.lines = nil
+	if len(r.verses) == 0 {
+		return
+	}
+
+	data := cachedSaveData()
+	heading := fmt.Sprintf("%s %d", r.verses[0].BookName, r.verses[0].Chapter)
+	r.lines = append(r.lines, readerLine{text: colorize(heading, boldText)}, readerLine{})
+
+	// Room for "> 176 " in front of the text
+	gutter := 6
+	wrapWidth := max(r.width-gutter-1, 10)
+
+	for _, verse := range r.verses {
+		color := ""
+		if i := data.FindHighlight(verse.ID); i >= 0 {
+			color = highlightColors[data.CategoryColor(data.Highlights[i].Category)]
+		}
+
+		for i, line := range WrapText(verse.Text, wrapWidth) {
+			prefix := strings.Repeat(" ", gutter)
+			if i == 0 {
+				prefix = fmt.Sprintf("  %3d ", verse.Verse)
+			}
+			if color != "" {
+				line = colorize(line, color)
+			}
+			r.lines = append(r.lines, readerLine{text: prefix + line, id: verse.ID})
+		}
+
+		for _, note := range data.Notes {
+			if note.Start != verse.ID {
+				continue
+			}
+			r.lines = append(r.lines, readerLine{text: strings.Repeat(" ", gutter) + colorize("Note on "+note.Reference+":", colorNote), id: verse.ID})
+			for _, text := range strings.Split(note.Text, "\n") {
+				for _, wrapped := range WrapText(text, wrapWidth-2) {
+					r.lines = append(r.lines, readerLine{text: strings.Repeat(" ", gutter) + colorize("| ", colorNote) + wrapped, id: verse.ID})
+				}
+			}
+		}
+	}
+}
+
+
+// The size of the terminal. Some terminals don't say, so guess the usual 80x24
+func readerSize() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 2 {
+		return 80, 24
+	}
+	return width, height
+}
+
+
+// How many lines of the chapter fit on the screen, leaving the bottom one for the status bar
+func (r *reader) pageSize() int {
+	return r.height - 1
+}
+
+
+// Keeps top from going past either end of the chapter
+func (r *reader) clampTop() {
+	r.top = min(r.top, len(r.lines)-r.pageSize())
+	r.top = max(r.top, 0)
+}
+
+
+// Scrolls just enough that all of the selected verse is on the screen
+func (r *reader) scrollToSelected() {
+	first, last := -1, -1
+	for i, line := range r.lines {
+		if line.id == r.id {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first == -1 {
+		return
+	}
+
+	// Show the heading too if it's the first verse
+	if r.id == r.verses[0].ID {
+		first = 0
+	}
+	if last >= r.top+r.pageSize() {
+		r.top = last - r.pageSize() + 1
+	}
+	if first < r.top {
+		r.top = first
+	}
+	r.clampTop()
+}
+
+
+// Draws the whole screen. It all goes out in one write so it doesn't flicker
+func (r *reader) draw() {
+	// The terminal might have been resized since last time
+	if width, height := readerSize(); width != r.width || height != r.height {
+		r.layout()
+		r.scrollToSelected()
+	}
+
+	var screen strings.Builder
+	screen.WriteString(cursorHome)
+
+	lines := r.lines
+	top := r.top
+	if r.help {
+		lines = nil
+		for _, text := range readerHelp {
+			lines = append(lines, readerLine{text: text})
+		}
+		top = 0
+	}
+
+	for row := 0; row < r.pageSize(); row++ {
+		screen.WriteString(clearLine)
+		if i := top + row; i < len(lines) {
+			line := lines[i]
+			// Mark the selected verse in the gutter
+			if line.id == r.id && line.id != 0 && strings.HasPrefix(line.text, " ") {
+				line.text = ">" + line.text[1:]
+			}
+			screen.WriteString(line.text)
+		}
+		screen.WriteString("\r\n")
+	}
+
+	screen.WriteString(clearLine)
+	screen.WriteString(r.statusBar())
+	// The cursor only needs to show while typing a command
+	if r.typing {
+		screen.WriteString(showCursor)
+	} else {
+		screen.WriteString(hideCursor)
+	}
+	os.Stdout.WriteString(screen.String())
+}
+
+
+// The bottom line. It's the command being typed, or the reference and a message (or a reminder of the keys)
+func (r *reader) statusBar() string {
+	if r.typing {
+		return ":" + string(r.command)
+	}
+
+	left := ""
+	for _, verse := range r.verses {
+		if verse.ID == r.id {
+			left = fmt.Sprintf(" %s %d:%d ", verse.BookName, verse.Chapter, verse.Verse)
+		}
+	}
+
+	// How far through the chapter the bottom of the screen is
+	percent := " "
+	if len(r.lines) > r.pageSize() {
+		percent = fmt.Sprintf("  %d%% ", min((r.top+r.pageSize())*100/len(r.lines), 100))
+	}
+
+	message := r.message
+	if message == "" {
+		message = "n/p verse  ]/[ chapter  : go to  ? help  q quit"
+	}
+
+	// If it doesn't all fit, drop the message, then the percent. The reference always stays
+	for _, right := range []string{message + percent, percent, ""} {
+		if gap := r.width - textWidth(left) - textWidth(right); gap >= 1 || right == "" {
+			return colorize(left+strings.Repeat(" ", max(gap, 0))+right, reverseVideo)
+		}
+	}
+	return ""
+}
+
+
+// Deals with one key press. It gives back true if it's time to quit
+func (r *reader) handleKey(key string) bool {
+	if r.typing {
+		return r.typeKey(key)
+	}
+
+	// Any key closes the help
+	if r.help {
+		r.help = false
+		return false
+	}
+
+	r.message = ""
+	switch key {
+	case "q", "Q", "\x03":
+		return true
+	case "j", "\x1b[B", "\x05":
+		r.top++
+	case "k", "\x1b[A", "\x19":
+		r.top--
+	case " ", "\x1b[6~", "\x06":
+		r.top += r.pageSize()
+	case "\x1b[5~", "\x02":
+		r.top -= r.pageSize()
+	case "g", "\x1b[H", "\x1b[1~":
+		r.top = 0
+	case "G", "\x1b[F", "\x1b[4~":
+		r.top = len(r.lines)
+	case "n", "\x1b[C":
+		r.move(r.id + 1)
+		return false
+	case "p", "\x1b[D":
+		r.move(r.id - 1)
+		return false
+	case "]":
+		r.move(r.verses[len(r.verses)-1].ID + 1)
+		return false
+	case "[":
+		// The last verse of the chapter before, then back to the start of that chapter
+		if r.move(r.verses[0].ID - 1) {
+			r.goTo(r.verses[0].ID)
+		}
+		return false
+	case "r":
+		r.random()
+		return false
+	case "f":
+		r.toggleFavorite()
+	case ":":
+		r.typing = true
+		r.command = nil
+	case "?", "h":
+		r.help = true
+	case "m", "a":
+		// FullScreenReader does these, because they need the terminal back
+		r.suspendKey = key
+	default:
+		r.message = fmt.Sprintf("Unknown key %q, press ? for help", key)
+	}
+	r.clampTop()
+	return false
+}
+
+
+// Goes to another verse, and says why if it can't. Gives back true if it moved
+func (r *reader) move(id int) bool {
+	if id < 1 {
+		r.message = "You are at the first verse"
+		return false
+	}
+	if err := r.goTo(id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			r.message = "You are at the last verse"
+		} else {
+			r.message = err.Error()
+		}
+		return false
+	}
+	return true
+}
+
+
+func (r *reader) random() {
+	verse, err := LookupRandomVerse(r.db)
+	if err != nil {
+		r.message = err.Error()
+		return
+	}
+	r.move(verse.ID)
+}
+
+
+// Adds the selected verse to the favorites, or takes it out if it's already there. No questions asked, it's just f again to undo
+func (r *reader) toggleFavorite() {
+	removed := false
+	err := UpdateUserData(func(ut *UserTx) error {
+		favorite, err := ut.ContainsFavorite(r.id)
+		if err != nil {
+			return err
+		}
+		if favorite {
+			removed = true
+			return ut.RemoveFavorite(r.id)
+		}
+		return ut.AddFavorite(r.id)
+	})
+
+	switch {
+	case err != nil:
+		r.message = "Error saving data: " + err.Error()
+	case removed:
+		r.message = "Removed from favorites"
+	default:
+		r.message = "Added to favorites"
+	}
+}
+
+
+// Typing a command after ':'. Enter runs it, escape (or backspace on nothing) cancels it
+func (r *reader) typeKey(key string) bool {
+	switch key {
+	case "\r", "\n":
+		r.typing = false
+		return r.runCommand(strings.TrimSpace(string(r.command)))
+	case "\x1b", "\x03":
+		r.typing = false
+	case "\x7f", "\x08":
+		if len(r.command) == 0 {
+			r.typing = false
+		} else {
+			r.command = r.command[:len(r.command)-1]
+		}
+	case "\x15": // Ctrl-U, clear the line
+		r.command = nil
+	default:
+		for _, c := range key {
+			if unicode.IsPrint(c) {
+				r.command = append(r.command, c)
+			}
+		}
+	}
+	return false
+}
+
+
+// Runs a command typed after ':'. Anything that isn't a command is a reference to go to
+func (r *reader) runCommand(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+	name := ""
+	if len(fields) > 1 {
+		name = fields[1]
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "q", "quit", "x":
+		return true
+	case "r", "random":
+		r.random()
+		return false
+	case "help":
+		r.help = true
+		return false
+	case "bookmark", "mark":
+		err := UpdateUserData(func(ut *UserTx) error {
+			return ut.SetBookmark(name, r.id)
+		})
+		if err != nil {
+			r.message = "Error saving data: " + err.Error()
+		} else {
+			r.message = "Bookmark " + bookmarkName(name) + " saved"
+		}
+		return false
+	case "go":
+		saveData, err := LoadSaveData()
+		if err != nil {
+			r.message = "Error loading data: " + err.Error()
+			return false
+		}
+		if id, ok := saveData.GetBookmark(name); ok {
+			r.move(id)
+		} else {
+			r.message = "There isn't a bookmark called " + bookmarkName(name)
+		}
+		return false
+	}
+
+	ref, err := ParseReference(command)
+	if err != nil {
+		r.message = err.Error()
+		return false
+	}
+	start, _, err := GetIdRange(r.db, ref.Ranges[0])
+	if err != nil {
+		r.message = err.Error()
+		return false
+	}
+	r.move(start)
+	return false
+}
+
+
+// Gives the terminal back for something that needs it (the highlight prompt or the note editor), then takes it over again
+func (r *reader) suspend(fd int, oldState **term.State, key string) {
+	fmt.Print(showCursor + leaveAltScreen)
+	term.Restore(fd, *oldState)
+
+	verse := GetVerseFromId(r.db, r.id)
+	fmt.Printf("\n%s %d:%d\n", verse.BookName, verse.Chapter, verse.Verse)
+	if key == "m" {
+		Highlights(r.db, r.id)
+		fmt.Print("Press any key to go back to the reader")
+		readKey()
+	} else {
+		EditNote(r.db, r.id, r.id)
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err == nil {
+		*oldState = state
+	}
+	fmt.Print(enterAltScreen + hideCursor)
+
+	// There might be a new highlight or note to show
+	r.layout()
+	r.scrollToSelected()
+}
+
+
+// Splits what was read from the terminal into separate keys. Usually it's one key, but pasting
+// or typing fast can send a few at once. Escape codes (arrows, PgUp, etc) stay together as one key.
+func splitKeys(input string) []string {
+	var keys []string
+	for len(input) > 0 {
+		if strings.HasPrefix(input, "\x1b[") || strings.HasPrefix(input, "\x1bO") {
+			end := 2
+			for end < len(input) && (input[end] < 0x40 || input[end] > 0x7e) {
+				end++
+			}
+			end = min(end+1, len(input))
+			// \x1bOA is the same as \x1b[A in some terminals
+			keys = append(keys, strings.Replace(input[:end], "\x1bO", "\x1b[", 1))
+			input = input[end:]
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(input)
+		keys = append(keys, input[:size])
+		input = input[size:]
+	}
+	return keys
+}
@@ -0,0 +1,128 @@
+package functions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+
+func TestSplitKeys(t *testing.T) {
+	tests := []struct {
+		input	string
+		want	[]string
+	}{
+		{"", nil},
+		{"jjk", []string{"j", "j", "k"}},
+		{"\x1b[A", []string{"\x1b[A"}},
+		// Some terminals send \x1bO instead of \x1b[ for the arrows
+		{"\x1bOB", []string{"\x1b[B"}},
+		{"\x1b[5~\x1b[6~", []string{"\x1b[5~", "\x1b[6~"}},
+		{"j\x1b[Bk", []string{"j", "\x1b[B", "k"}},
+		{"\x1b[1;5C", []string{"\x1b[1;5C"}},
+		// Escape on its own, and a sequence that got cut off
+		{"\x1b", []string{"\x1b"}},
+		{"\x1b[", []string{"\x1b["}},
+		{"q\x1b", []string{"q", "\x1b"}},
+		// Letters that take more than one byte stay together
+		{"é:", []string{"é", ":"}},
+		{"\r\x7f", []string{"\r", "\x7f"}},
+	}
+
+	for _, test := range tests {
+		if got := splitKeys(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitKeys(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+
+// Sends the keys to the reader one at a time, and gives back true if one of them quit it
+func pressKeys(r *reader, keys string) bool {
+	for _, key := range splitKeys(keys) {
+		if r.handleKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+
+func TestReaderMoving(t *testing.T) {
+	useTempUserDb(t)
+	r := &reader{db: newTestBibleDb(t)}
+	if err := r.goTo(57); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keys	string
+		want	int
+		message	string
+	}{
+		{"n", 58, ""},
+		{"\x1b[C\x1b[C", 60, ""},
+		{"p", 59, ""},
+		// The next chapter, and back to the start of this one
+		{"]", 108, ""},
+		{"[", 57, ""},
+		{":John 3:16\r", 148, ""},
+		{":jude 1:25\r]", 193, "You are at the last verse"},
+		{":Gen 1:1\rp", 1, "You are at the first verse"},
+		{":Jhon 3\r", 1, "did you mean John?"},
+		// Escape cancels the command
+		{":John 3\x1b", 1, ""},
+		{"z", 1, "Unknown key"},
+	}
+
+	for _, test := range tests {
+		if pressKeys(r, test.keys) {
+			t.Fatalf("%q quit the reader", test.keys)
+		}
+		if r.id != test.want || !strings.Contains(r.message, test.message) {
+			t.Errorf("after %q the reader is on %d (%q), want %d (%q)", test.keys, r.id, r.message, test.want, test.message)
+		}
+		if r.typing {
+			t.Errorf("after %q the reader is still typing a command", test.keys)
+		}
+	}
+
+	// The selected verse is always on the screen
+	pressKeys(r, ":John 1:51\r")
+	if first, last := r.top, r.top+r.pageSize(); r.lines[first].id > 107 || r.lines[last-1].id < 107 {
+		t.Errorf("John 1:51 is selected but lines %d-%d are showing", first, last)
+	}
+
+	for _, keys := range []string{"q", ":quit\r"} {
+		if !pressKeys(r, keys) {
+			t.Errorf("%q didn't quit the reader", keys)
+		}
+	}
+}
+
+
+func TestReaderSaving(t *testing.T) {
+	useTempUserDb(t)
+	r := &reader{db: newTestBibleDb(t)}
+	if err := r.goTo(148); err != nil {
+		t.Fatal(err)
+	}
+
+	pressKeys(r, "f")
+	if saveData, _ := LoadSaveData(); !saveData.ContainsFavorite(148) || r.message != "Added to favorites" {
+		t.Errorf("f didn't add John 3:16 to the favorites (%q)", r.message)
+	}
+	pressKeys(r, "f")
+	if saveData, _ := LoadSaveData(); saveData.ContainsFavorite(148) || r.message != "Removed from favorites" {
+		t.Errorf("f again didn't take John 3:16 out of the favorites (%q)", r.message)
+	}
+
+	pressKeys(r, ":bookmark evening\r:Gen 1:1\r:go evening\r")
+	if r.id != 148 {
+		t.Errorf(":go evening went to %d, want 148", r.id)
+	}
+	pressKeys(r, ":go morning\r")
+	if r.id != 148 || r.message != "There isn't a bookmark called morning" {
+		t.Errorf(":go morning went to %d (%q)", r.id, r.message)
+	}
+}
@@ -0,0 +1,473 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"sync"
+	"time"
+	"context"
+	"path/filepath"
+	"database/sql"
+	"encoding/json"
+)
+
+
+// Everything you save (bookmarks, favorites, notes, highlights) goes in its own sqlite database, user.db, next to where
+// bible-data.json used to be. It used to all be in bible-data.json, but that got rewritten in full on every change, so
+// two bible programs running at once could undo each other's changes. If there is a bible-data.json it gets imported
+// the first time user.db is made, then renamed to bible-data.json.imported.
+//
+// To change the tables, add a migration to the end of userMigrations. Never change one that is already there,
+// because people's databases have already run it. PRAGMA user_version is how many have been run.
+var userMigrations = []string{
+	// 1: Everything that was in bible-data.json
+	`CREATE TABLE bookmarks (
+		name		TEXT PRIMARY KEY,
+		verse_id	INTEGER NOT NULL,
+		updated		TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE favorites (
+		verse_id	INTEGER PRIMARY KEY,
+		note		TEXT NOT NULL DEFAULT '',
+		added		TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE favorite_tags (
+		verse_id	INTEGER NOT NULL,
+		tag			TEXT NOT NULL,
+		PRIMARY KEY (verse_id, tag)
+	);
+	CREATE TABLE notes (
+		start_id	INTEGER NOT NULL,
+		end_id		INTEGER NOT NULL,
+		reference	TEXT NOT NULL,
+		text		TEXT NOT NULL,
+		created		TEXT NOT NULL DEFAULT '',
+		updated		TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (start_id, end_id)
+	);
+	CREATE TABLE highlights (
+		verse_id	INTEGER PRIMARY KEY,
+		category	TEXT NOT NULL,
+		created		TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE highlight_colors (
+		category	TEXT PRIMARY KEY,
+		color		TEXT NOT NULL
+	);`,
+
+	// 2: Reading history (see history.go)
+	`CREATE TABLE history (
+		id			INTEGER PRIMARY KEY AUTOINCREMENT,
+		start_id	INTEGER NOT NULL,
+		end_id		INTEGER NOT NULL,
+		reference	TEXT NOT NULL,
+		viewed		TEXT NOT NULL,
+		source		TEXT NOT NULL
+	);
+	CREATE INDEX history_viewed ON history (viewed);`,
+
+	// 3: Reading plan progress, which was in plan-progress.json (see plans.go)
+	`CREATE TABLE reading_plan (
+		id			INTEGER PRIMARY KEY CHECK (id = 1),
+		name		TEXT NOT NULL,
+		start		TEXT NOT NULL
+	);
+	CREATE TABLE plan_progress (
+		day			INTEGER PRIMARY KEY,
+		date		TEXT NOT NULL
+	);
+	CREATE TABLE plan_progress_ranges (
+		day			INTEGER NOT NULL,
+		start_id	INTEGER NOT NULL,
+		end_id		INTEGER NOT NULL
+	);
+	CREATE INDEX plan_progress_ranges_day ON plan_progress_ranges (day);`,
+}
+
+
+// The user database, opened the first time it's needed and then kept open. The lock is so that the first
+// requests to serve (which can come in at the same time) don't both open it and run the migrations
+var userDb *sql.DB
+var userDbLock sync.Mutex
+
+
+// GetUserDbPath gives back where the user database is (~/.local/share/bible/user.db)
+func GetUserDbPath() string {
+	return filepath.Join(filepath.Dir(GetDataFilePath()), "user.db")
+}
+
+
+// OpenUserDb opens the user database, making it and running any migrations it needs first
+func OpenUserDb() (*sql.DB, error) {
+	userDbLock.Lock()
+	defer userDbLock.Unlock()
+	if userDb != nil {
+		return userDb, nil
+	}
+
+	// Wait for other bible programs instead of failing straight away, and take the write lock at the start
+	// of every transaction so a read then write in one can't be changed underneath it
+	db, err := sql.Open(DriverName, "file:"+GetUserDbPath()+"?_busy_timeout=5000&_txlock=immediate&_foreign_keys=1")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrateUserDb(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error setting up %s: %v", GetUserDbPath(), err)
+	}
+
+	userDb = db
+	return userDb, nil
+}
+
+
+// Runs the migrations that haven't been run yet, all in one transaction. The files that things used to be saved in
+// (bible-data.json and plan-progress.json) get imported when the tables for them are made
+func migrateUserDb(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version >= len(userMigrations) {
+		return nil
+	}
+
+	for i := version; i < len(userMigrations); i++ {
+		if _, err := tx.Exec(userMigrations[i]); err != nil {
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+	}
+	// PRAGMA can't have a ? in it, but this is always just a number
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(userMigrations))); err != nil {
+		return err
+	}
+
+	var imported []string
+	if version < 1 {
+		if ok, err := importDataFile(tx); err != nil {
+			return err
+		} else if ok {
+			imported = append(imported, GetDataFilePath())
+		}
+	}
+	if version < 3 {
+		if ok, err := importPlanProgress(tx); err != nil {
+			return err
+		} else if ok {
+			imported = append(imported, GetPlanProgressPath())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Only move the old files out of the way once everything is safely in the database
+	for _, path := range imported {
+		if err := os.Rename(path, path+".imported"); err != nil {
+			fmt.Printf("Imported %s, but couldn't rename it: %v\n", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+
+// Imports bible-data.json into a new user database. It gives back false if there wasn't one
+func importDataFile(tx *sql.Tx) (bool, error) {
+	saveData := &SaveData{}
+	if err := saveData.Load(GetDataFilePath()); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("Can't import %s: %v", GetDataFilePath(), err)
+	}
+
+	return true, importSaveData(tx, saveData)
+}
+
+
+// Imports plan-progress.json into the reading plan tables. It gives back false if there wasn't one
+func importPlanProgress(tx *sql.Tx) (bool, error) {
+	data, err := os.ReadFile(GetPlanProgressPath())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	progress := PlanProgress{}
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return false, fmt.Errorf("Can't import %s: %v", GetPlanProgressPath(), err)
+	}
+	if progress.Plan == "" {
+		return true, nil
+	}
+
+	ut := &UserTx{tx: tx}
+	if err := ut.StartPlan(progress.Plan, progress.Start); err != nil {
+		return false, err
+	}
+	for _, day := range progress.Done {
+		if err := ut.MarkPlanDay(day); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+
+// LoadSaveData reads everything that has been saved
+func LoadSaveData() (*SaveData, error) {
+	db, err := OpenUserDb()
+	if err != nil {
+		return nil, err
+	}
+
+	// Transactions from db.Begin() take the write lock straight away (_txlock=immediate), which reading doesn't need,
+	// so this starts a plain deferred one itself. It still makes sure all the tables are read at the same point
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN DEFERRED"); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(ctx, "ROLLBACK")
+
+	return readSaveData(conn)
+}
+
+
+// UserTx is a transaction on the user database. Every change (ie SetBookmark or TagFavorite) is its own
+// INSERT, UPDATE or DELETE on only the rows it changes, so two bible programs changing different things at once
+// can't undo each other. The changes are in the files for each thing (bookmarks.go, favorites.go, notes.go, highlights.go)
+type UserTx struct {
+	tx	*sql.Tx
+}
+
+
+// UpdateUserData runs update in one transaction. If update gives back an error, nothing is saved
+func UpdateUserData(update func(ut *UserTx) error) error {
+	db, err := OpenUserDb()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := update(&UserTx{tx: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// The notes/highlights might have changed, so load them again next time they are printed
+	clearCachedSaveData()
+	return nil
+}
+
+
+// Runs a change and gives back whether it changed any rows, ie false if there wasn't a bookmark with that name to remove
+func (ut *UserTx) execChanged(query string, args ...any) (bool, error) {
+	result, err := ut.tx.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+
+// Reads all the tables into a SaveData
+func readSaveData(conn *sql.Conn) (*SaveData, error) {
+	sd := &SaveData{}
+
+	err := queryRows(conn, "SELECT name, verse_id, updated FROM bookmarks ORDER BY name", func(rows *sql.Rows) error {
+		var b NamedBookmark
+		var updated string
+		if err := rows.Scan(&b.Name, &b.ID, &updated); err != nil {
+			return err
+		}
+		b.Updated = parseTime(updated)
+		sd.Bookmarks = append(sd.Bookmarks, b)
+		if b.Name == defaultBookmark {
+			sd.Bookmark = b.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRows(conn, "SELECT verse_id, note, added FROM favorites ORDER BY verse_id", func(rows *sql.Rows) error {
+		var detail FavoriteDetail
+		var added string
+		if err := rows.Scan(&detail.ID, &detail.Note, &added); err != nil {
+			return err
+		}
+		detail.Added = parseTime(added)
+		sd.Favorites = append(sd.Favorites, detail.ID)
+		sd.FavoriteDetails = append(sd.FavoriteDetails, detail)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRows(conn, "SELECT verse_id, tag FROM favorite_tags ORDER BY verse_id, tag", func(rows *sql.Rows) error {
+		var id int
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		detail := sd.favoriteDetail(id)
+		detail.Tags = append(detail.Tags, tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRows(conn, "SELECT start_id, end_id, reference, text, created, updated FROM notes ORDER BY start_id, end_id", func(rows *sql.Rows) error {
+		var note Note
+		var created, updated string
+		if err := rows.Scan(&note.Start, &note.End, &note.Reference, &note.Text, &created, &updated); err != nil {
+			return err
+		}
+		note.Created = parseTime(created)
+		note.Updated = parseTime(updated)
+		sd.Notes = append(sd.Notes, note)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRows(conn, "SELECT verse_id, category, created FROM highlights ORDER BY verse_id", func(rows *sql.Rows) error {
+		var h Highlight
+		var created string
+		if err := rows.Scan(&h.ID, &h.Category, &created); err != nil {
+			return err
+		}
+		h.Created = parseTime(created)
+		sd.Highlights = append(sd.Highlights, h)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryRows(conn, "SELECT category, color FROM highlight_colors", func(rows *sql.Rows) error {
+		var category, color string
+		if err := rows.Scan(&category, &color); err != nil {
+			return err
+		}
+		if sd.HighlightColors == nil {
+			sd.HighlightColors = make(map[string]string)
+		}
+		sd.HighlightColors[category] = color
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sd, nil
+}
+
+
+// Writes everything from bible-data.json into the new (empty) tables. This is only for the import,
+// everything else changes one row at a time with UpdateUserData
+func importSaveData(tx *sql.Tx, sd *SaveData) error {
+	for _, b := range sd.AllBookmarks() {
+		// The default bookmark might have been moved with SetBookmark, which doesn't know about the named ones
+		if b.Name == defaultBookmark && sd.Bookmark != 0 && b.ID != sd.Bookmark {
+			b.ID = sd.Bookmark
+			b.Updated = time.Now()
+		}
+		if _, err := tx.Exec("INSERT INTO bookmarks (name, verse_id, updated) VALUES (?, ?, ?)", b.Name, b.ID, formatTime(b.Updated)); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range sd.Favorites {
+		detail := sd.GetFavoriteDetail(id)
+		if _, err := tx.Exec("INSERT OR IGNORE INTO favorites (verse_id, note, added) VALUES (?, ?, ?)", id, detail.Note, formatTime(detail.Added)); err != nil {
+			return err
+		}
+		for _, tag := range detail.Tags {
+			if _, err := tx.Exec("INSERT OR IGNORE INTO favorite_tags (verse_id, tag) VALUES (?, ?)", id, tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, note := range sd.Notes {
+		_, err := tx.Exec("INSERT OR REPLACE INTO notes (start_id, end_id, reference, text, created, updated) VALUES (?, ?, ?, ?, ?, ?)",
+			note.Start, note.End, note.Reference, note.Text, formatTime(note.Created), formatTime(note.Updated))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, h := range sd.Highlights {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO highlights (verse_id, category, created) VALUES (?, ?, ?)", h.ID, h.Category, formatTime(h.Created)); err != nil {
+			return err
+		}
+	}
+
+	for category, color := range sd.HighlightColors {
+		if _, err := tx.Exec("INSERT OR REPLACE INTO highlight_colors (category, color) VALUES (?, ?)", category, color); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+
+// Runs a query and calls scan for every row
+func queryRows(conn *sql.Conn, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := conn.QueryContext(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+
+// Times are saved as text (RFC 3339), and an empty string is no time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
@@ -0,0 +1,481 @@
+package functions
+
+import (
+	"os"
+	"fmt"
+	"sync"
+	"time"
+	"testing"
+	"path/filepath"
+	"database/sql"
+)
+
+
+// Points the user data at an empty folder for the test, and gives back the folder
+func useTempUserDb(t *testing.T) string {
+	t.Helper()
+	closeUserDb := func() {
+		if userDb != nil {
+			userDb.Close()
+		}
+		userDb = nil
+		clearCachedSaveData()
+	}
+	closeUserDb()
+	t.Cleanup(closeUserDb)
+
+	t.Setenv("HOME", t.TempDir())
+	return filepath.Dir(GetDataFilePath())
+}
+
+
+// Another connection to user.db, like a second bible program running at the same time would have
+func openSecondUserDb(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(DriverName, "file:"+GetUserDbPath()+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+
+// Saves what update changes, and gives back everything that is saved after
+func updateAndLoad(t *testing.T, update func(ut *UserTx) error) *SaveData {
+	t.Helper()
+	if err := UpdateUserData(update); err != nil {
+		t.Fatal(err)
+	}
+	saveData, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saveData
+}
+
+
+func userVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+
+func TestMigrateNewUserDb(t *testing.T) {
+	useTempUserDb(t)
+
+	db, err := OpenUserDb()
+	if err != nil {
+		t.Fatalf("OpenUserDb() gave an error: %v", err)
+	}
+	if version := userVersion(t, db); version != len(userMigrations) {
+		t.Errorf("user_version = %d, want %d", version, len(userMigrations))
+	}
+
+	saveData, err := LoadSaveData()
+	if err != nil {
+		t.Fatalf("LoadSaveData() gave an error: %v", err)
+	}
+	if len(saveData.AllBookmarks()) != 0 || len(saveData.Favorites) != 0 || len(saveData.Notes) != 0 || len(saveData.Highlights) != 0 {
+		t.Errorf("A new user.db should be empty, got %+v", saveData)
+	}
+
+	// Running the migrations again doesn't do anything
+	if err := migrateUserDb(db); err != nil {
+		t.Errorf("migrateUserDb() a second time gave an error: %v", err)
+	}
+}
+
+
+func TestMigrateImportsJsonFiles(t *testing.T) {
+	dir := useTempUserDb(t)
+
+	dataFile := `{
+		"bookmark": 43,
+		"bookmarks": [{"name": "devotions", "id": 26137, "updated": "2026-10-01T08:00:00Z"}],
+		"favorites": [26137, 1],
+		"favoriteDetails": [{"id": 26137, "tags": ["comfort", "memory"], "note": "for hard days", "added": "2026-09-01T08:00:00Z"}],
+		"notes": [{"start": 1, "end": 3, "reference": "Genesis 1:1-3", "text": "In the beginning", "created": "2026-09-02T08:00:00Z", "updated": "2026-09-02T08:00:00Z"}],
+		"highlights": [{"id": 2, "category": "promise"}, {"id": 3, "category": "hope"}],
+		"highlightColors": {"hope": "blue"}
+	}`
+	progressFile := `{"plan": "year", "start": "2026-10-01", "done": [
+		{"day": 1, "date": "2026-10-01", "ranges": [[1, 31], [32, 56]]},
+		{"day": 3, "date": "2026-10-03", "ranges": []}
+	]}`
+	if err := os.WriteFile(filepath.Join(dir, "bible-data.json"), []byte(dataFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "plan-progress.json"), []byte(progressFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	saveData, err := LoadSaveData()
+	if err != nil {
+		t.Fatalf("LoadSaveData() gave an error: %v", err)
+	}
+
+	if id, ok := saveData.GetBookmark(""); !ok || id != 43 {
+		t.Errorf("default bookmark = %d, %v, want 43", id, ok)
+	}
+	if id, ok := saveData.GetBookmark("devotions"); !ok || id != 26137 {
+		t.Errorf("devotions bookmark = %d, %v, want 26137", id, ok)
+	}
+	if fmt.Sprint(saveData.Favorites) != "[1 26137]" {
+		t.Errorf("favorites = %v, want [1 26137]", saveData.Favorites)
+	}
+	detail := saveData.GetFavoriteDetail(26137)
+	if fmt.Sprint(detail.Tags) != "[comfort memory]" || detail.Note != "for hard days" || detail.Added.IsZero() {
+		t.Errorf("favorite detail = %+v", detail)
+	}
+	if len(saveData.Notes) != 1 || saveData.Notes[0].Text != "In the beginning" || saveData.Notes[0].End != 3 {
+		t.Errorf("notes = %+v", saveData.Notes)
+	}
+	if len(saveData.Highlights) != 2 || saveData.CategoryColor("hope") != "blue" {
+		t.Errorf("highlights = %+v, colors = %v", saveData.Highlights, saveData.HighlightColors)
+	}
+
+	progress := &PlanProgress{}
+	if err := progress.Load(); err != nil {
+		t.Fatalf("PlanProgress.Load() gave an error: %v", err)
+	}
+	if progress.Plan != "year" || progress.Start != "2026-10-01" || len(progress.Done) != 2 {
+		t.Fatalf("plan progress = %+v", progress)
+	}
+	if fmt.Sprint(progress.Done[0].Ranges) != "[[1 31] [32 56]]" || !progress.IsDone(3) || len(progress.Done[1].Ranges) != 0 {
+		t.Errorf("plan days = %+v", progress.Done)
+	}
+	if progress.VersesRead() != 56 {
+		t.Errorf("plan verses read = %d, want 56", progress.VersesRead())
+	}
+
+	// The old files get moved out of the way so they don't get imported again
+	for _, name := range []string{"bible-data.json", "plan-progress.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s is still there after importing it", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, name+".imported")); err != nil {
+			t.Errorf("%s.imported isn't there: %v", name, err)
+		}
+	}
+}
+
+
+func TestMigrateBadJsonFile(t *testing.T) {
+	dir := useTempUserDb(t)
+	os.WriteFile(filepath.Join(dir, "bible-data.json"), []byte("{not json"), 0644)
+
+	// Nothing is made, so it can be fixed and imported next time
+	if _, err := OpenUserDb(); err == nil {
+		t.Fatalf("OpenUserDb() with a broken bible-data.json should give an error")
+	}
+	if version := userVersion(t, openSecondUserDb(t)); version != 0 {
+		t.Errorf("user_version = %d after a failed import, want 0", version)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bible-data.json")); err != nil {
+		t.Errorf("bible-data.json got moved even though it wasn't imported: %v", err)
+	}
+}
+
+
+func TestMigrateFromVersion2(t *testing.T) {
+	dir := useTempUserDb(t)
+
+	// A user.db from before the reading plan was in it, with a favorite already saved
+	db := openSecondUserDb(t)
+	for _, migration := range userMigrations[:2] {
+		if _, err := db.Exec(migration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO favorites (verse_id) VALUES (5); PRAGMA user_version = 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// bible-data.json was already imported back at version 1, so a new one shouldn't be
+	os.WriteFile(filepath.Join(dir, "bible-data.json"), []byte(`{"favorites": [7]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "plan-progress.json"), []byte(`{"plan": "nt90", "start": "2026-10-10", "done": []}`), 0644)
+
+	saveData, err := LoadSaveData()
+	if err != nil {
+		t.Fatalf("LoadSaveData() gave an error: %v", err)
+	}
+	if fmt.Sprint(saveData.Favorites) != "[5]" {
+		t.Errorf("favorites = %v, want [5]", saveData.Favorites)
+	}
+	if version := userVersion(t, db); version != len(userMigrations) {
+		t.Errorf("user_version = %d, want %d", version, len(userMigrations))
+	}
+
+	progress := &PlanProgress{}
+	if err := progress.Load(); err != nil || progress.Plan != "nt90" {
+		t.Errorf("plan progress = %+v, %v, want nt90", progress, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bible-data.json")); err != nil {
+		t.Errorf("bible-data.json shouldn't have been touched: %v", err)
+	}
+}
+
+
+func TestConcurrentUpdates(t *testing.T) {
+	useTempUserDb(t)
+	second := openSecondUserDb(t)
+
+	// Half of them go through this program's connection, and half through another one like a second bible program would.
+	// They all open user.db at the same time first, like the first requests to serve can
+	const updates = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, updates)
+	opened := make(chan *sql.DB, updates)
+	for i := 1; i <= updates; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			<-start
+			db, err := OpenUserDb()
+			if err != nil {
+				errs <- err
+				return
+			}
+			opened <- db
+
+			if id%2 == 0 {
+				errs <- UpdateUserData(func(ut *UserTx) error {
+					return ut.TagFavorite(id, "together")
+				})
+				return
+			}
+
+			tx, err := second.Begin()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer tx.Rollback()
+			ut := &UserTx{tx: tx}
+			if err := ut.TagFavorite(id, "together"); err != nil {
+				errs <- err
+				return
+			}
+			errs <- tx.Commit()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	close(opened)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("update gave an error: %v", err)
+		}
+	}
+	for db := range opened {
+		if db != userDb {
+			t.Errorf("OpenUserDb() opened more than one connection")
+			break
+		}
+	}
+
+	saveData, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saveData.Favorites) != updates || saveData.FavoriteTags()["together"] != updates {
+		t.Errorf("got %d favorites (%d tagged), want %d", len(saveData.Favorites), saveData.FavoriteTags()["together"], updates)
+	}
+}
+
+
+func TestLoadWhileAnotherProgramWrites(t *testing.T) {
+	useTempUserDb(t)
+	if err := UpdateUserData(func(ut *UserTx) error { return ut.AddFavorite(5) }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another bible program is in the middle of changing something, which reading shouldn't have to wait for
+	second := openSecondUserDb(t)
+	tx, err := second.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO favorites (verse_id) VALUES (6)"); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	saveData, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(started); waited > time.Second {
+		t.Errorf("LoadSaveData() waited %s for the other program", waited)
+	}
+	if len(saveData.Favorites) != 1 {
+		t.Errorf("favorites = %v, want only 5 until the other program commits", saveData.Favorites)
+	}
+}
+
+
+func TestCachedSaveDataWhileSaving(t *testing.T) {
+	useTempUserDb(t)
+
+	// bible serve prints verses (which reads the cached data) while other requests save things
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(2)
+		go func(id int) {
+			defer wg.Done()
+			UpdateUserData(func(ut *UserTx) error { return ut.SetHighlight(id, "promise") })
+		}(i)
+		go func() {
+			defer wg.Done()
+			cachedSaveData()
+		}()
+	}
+	wg.Wait()
+
+	if data := cachedSaveData(); len(data.Highlights) != 10 {
+		t.Errorf("the cached data has %d highlights after saving, want 10", len(data.Highlights))
+	}
+}
+
+
+func TestUpdatesOnlyChangeTheirRows(t *testing.T) {
+	useTempUserDb(t)
+
+	// Loaded before the other program changes anything
+	before, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before.Favorites) != 0 {
+		t.Fatalf("favorites = %v, want none", before.Favorites)
+	}
+
+	// Another bible program adds a favorite and a note
+	second := openSecondUserDb(t)
+	if _, err := second.Exec("INSERT INTO favorites (verse_id) VALUES (10); INSERT INTO notes (start_id, end_id, reference, text) VALUES (1, 1, 'Genesis 1:1', 'x')"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = UpdateUserData(func(ut *UserTx) error {
+		if err := ut.SetBookmark("devotions", 20); err != nil {
+			return err
+		}
+		return ut.SetHighlight(30, "promise")
+	})
+	if err != nil {
+		t.Fatalf("UpdateUserData() gave an error: %v", err)
+	}
+
+	after, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ContainsFavorite(10) || len(after.Notes) != 1 {
+		t.Errorf("the other program's changes got lost: favorites %v, notes %+v", after.Favorites, after.Notes)
+	}
+	if id, _ := after.GetBookmark("devotions"); id != 20 || after.FindHighlight(30) < 0 {
+		t.Errorf("the changes weren't saved: bookmarks %+v, highlights %+v", after.Bookmarks, after.Highlights)
+	}
+
+	// An error in the update means nothing in it is saved
+	err = UpdateUserData(func(ut *UserTx) error {
+		if err := ut.RemoveFavorite(10); err != nil {
+			return err
+		}
+		return fmt.Errorf("changed my mind")
+	})
+	if err == nil {
+		t.Errorf("UpdateUserData() should give back the error from the update")
+	}
+	if saveData, _ := LoadSaveData(); !saveData.ContainsFavorite(10) {
+		t.Errorf("the favorite was removed even though the update failed")
+	}
+}
+
+
+func TestUserTxChanges(t *testing.T) {
+	useTempUserDb(t)
+
+	err := UpdateUserData(func(ut *UserTx) error {
+		ut.SetBookmark("", 1)
+		ut.SetBookmark("Devotions ", 2)
+		ut.SetBookmark("devotions", 3)
+		ut.TagFavorite(5, "Memory 2026")
+		ut.SetFavoriteNote(5, "  a note  ")
+		ut.TagFavorite(6, "")
+		ut.SetNote(Note{Start: 7, End: 8, Reference: "Genesis 1:7-8", Text: "first"})
+		ut.SetNote(Note{Start: 7, End: 8, Reference: "Genesis 1:7-8", Text: "second"})
+		ut.SetHighlight(9, "promise")
+		ut.SetHighlight(9, "command")
+		return ut.SetCategoryColor("hope", "blue")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saveData, err := LoadSaveData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saveData.Bookmarks) != 2 || saveData.Bookmark != 1 {
+		t.Errorf("bookmarks = %+v (default %d)", saveData.Bookmarks, saveData.Bookmark)
+	}
+	if id, _ := saveData.GetBookmark("devotions"); id != 3 {
+		t.Errorf("devotions bookmark = %d, want 3", id)
+	}
+	detail := saveData.GetFavoriteDetail(5)
+	if fmt.Sprint(detail.Tags) != "[memory-2026]" || detail.Note != "a note" {
+		t.Errorf("favorite 5 = %+v", detail)
+	}
+	if !saveData.ContainsFavorite(6) {
+		t.Errorf("favorite 6 wasn't added")
+	}
+	if len(saveData.Notes) != 1 || saveData.Notes[0].Text != "second" {
+		t.Errorf("notes = %+v", saveData.Notes)
+	}
+	if i := saveData.FindHighlight(9); i < 0 || saveData.Highlights[i].Category != "command" {
+		t.Errorf("highlights = %+v", saveData.Highlights)
+	}
+
+	var removed []bool
+	err = UpdateUserData(func(ut *UserTx) error {
+		for _, remove := range []func() (bool, error){
+			func() (bool, error) { return ut.RemoveBookmark("DEVOTIONS") },
+			func() (bool, error) { return ut.RemoveBookmark("nope") },
+			func() (bool, error) { return ut.UntagFavorite(5, "memory 2026") },
+			func() (bool, error) { return ut.UntagFavorite(5, "memory 2026") },
+			func() (bool, error) { return ut.RemoveNote(7, 8) },
+			func() (bool, error) { return ut.RemoveNote(7, 9) },
+		} {
+			ok, err := remove()
+			if err != nil {
+				return err
+			}
+			removed = append(removed, ok)
+		}
+		ut.RemoveHighlight(9)
+		return ut.RemoveFavorite(6)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(removed) != "[true false true false true false]" {
+		t.Errorf("removed = %v, want [true false true false true false]", removed)
+	}
+
+	saveData, _ = LoadSaveData()
+	if _, ok := saveData.GetBookmark("devotions"); ok || len(saveData.Notes) != 0 || len(saveData.Highlights) != 0 {
+		t.Errorf("things weren't removed: %+v", saveData)
+	}
+	if saveData.ContainsFavorite(6) || !saveData.ContainsFavorite(5) || len(saveData.GetFavoriteDetail(5).Tags) != 0 {
+		t.Errorf("favorites = %v, details = %+v", saveData.Favorites, saveData.FavoriteDetails)
+	}
+}
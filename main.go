@@ -13,11 +13,13 @@ import (
 	"flag"
 	_ "embed"
 	"os/exec"
-	"strconv"
 	"strings"
+	"time"
+	"strconv"
 	"database/sql"
 	_ "github.com/mattn/go-sqlite3"
 	f "bible/functions"
+	"bible/client"
 )
 
 // This struct is to reference the sql database
@@ -64,30 +66,39 @@ func main() {
 	// Version number
 	versionNumber := "v0.2.6"
 
-	// Create a temporary file to hold the embedded database
-	tmpFile, err := os.CreateTemp("", "kjv.db")
+	// The embedded database has to be in a file for sqlite to open it. It gets extracted to the cache folder
+	// the first time, then every run after that just uses it
+	dbPath, err := f.CacheDatabase("kjv", embeddedDb)
 	if err != nil {
-		log.Fatal(err)
-	}
-	defer os.Remove(tmpFile.Name()) // Clean up the temp file afterwards
-
-	// Write the embedded database to the temporary file
-	if _, err := tmpFile.Write(embeddedDb); err != nil {
-		log.Fatal(err)
-	}
-	if err := tmpFile.Close(); err != nil {
-		log.Fatal(err)
+		// Can't use the cache folder for some reason, so fall back to a temporary file just for this run
+		dbPath, err = extractTempDatabase()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.Remove(dbPath) // Clean up the temp file afterwards
 	}
 
 	// Command line flags
 	interactive := flag.Bool("i", false, "Enable interactive mode")
+	tui := flag.Bool("tui", false, "Full screen reader, a chapter at a time, ie \"bible --tui John 3\"")
 	list := flag.Bool("l", false, "List Info")
 	version := flag.Bool("v", false, "Print Version")
 	random := flag.Bool("r", false, "Print random verse")
 	search := flag.Bool("s", false, "search for term")
 	exact := flag.Bool("e", false, "search for exact term, use with -s")
+	rank := flag.Bool("rank", false, "Show the best search matches first instead of in bible order, use with -s")
+	count := flag.Bool("count", false, "Only print how many matches there are in each book, use with -s")
+	limit := flag.Int("limit", 0, "Only show this many search results, use with -s")
+	offset := flag.Int("offset", 0, "Skip this many search results (for the next page), use with -s")
+	regex := flag.Bool("regex", false, "Search with a regular expression, ie -s --regex '\\b(lov(e|ed|eth))\\b'")
+	in := flag.String("in", "", "Only search in a book, group or range, ie NT, Gospels, \"Isaiah 40-66\" (\"list\" to see the groups), use with -s")
 	favorite := flag.Bool("f", false, "List favorite verses")
-	//test := flag.Bool("t", false, "Test function, for testing.")
+	tag := flag.String("tag", "", "Only list favorites with this tag, use with -f")
+	translation := flag.String("t", "KJV", "Translation to use, or \"list\" to see them all")
+	flag.StringVar(translation, "translation", "KJV", "Same as -t")
+	compare := flag.String("compare", "", "Show translations side by side, ie --compare KJV,WEB")
+	format := flag.String("format", "text", "How to print verses: text, json, jsonl, csv or md")
+	//test := flag.Bool("test", false, "Test function, for testing.")
 
   	// This changes the help/usage info when -h is used.
 	flag.Usage = func() {
@@ -95,52 +106,783 @@ func main() {
 		description := "%s\n\n" +
 		"This program lets you read the bible in the command line.\n\n" +
 		" Basic Usage:\n\n" +
-		" \"bible Genesis 1 1\" or \"bible -i\"\n\n" +
+		" \"bible Genesis 1 1\", \"bible John 3:16-18\", \"bible 1 John 5:10; Jude 5\" or \"bible -i\"\n\n" +
+		" Read a chapter at a time full screen with \"bible --tui\" or \"bible --tui John 3\" (j/k scroll, n/p verse, ]/[ chapter, : to go to a verse)\n\n" +
+		" Search with -s, ie \"bible -s love mercy\", \"bible -s bless* OR grace\" or \"bible -s faith NEAR/5 hope NOT fear\"\n\n" +
+		" Other translations can be used with -t, ie \"bible -t WEB John 3:16\" (\"bible -t list\" to see them all)\n\n" +
+		" Run a JSON API server with \"bible serve --addr :8080\", ie GET /passage?ref=John+3:16-18, /search?q=love, /random, /books, /books/John/chapters, /favorites\n\n" +
+		" Reading plans with \"bible plan start year\", \"bible plan today\" and \"bible plan done\" (\"bible plan\" for more)\n\n" +
+		" Notes on verses with \"bible notes add John 3:16\" (or 'a' in -i), \"bible notes\" to list them and \"bible notes --search grace\"\n\n" +
+		" Highlight verses with 'm' in -i, then list them with \"bible highlights --category promise\"\n\n" +
+		" Named bookmarks with \"bible bookmark set devotions John 3:16\", \"bible bookmark go devotions\" and \"bible bookmark list\"\n\n" +
+		" Favorites can have tags and notes: \"bible favorite add John 3:16 --tag comfort\", then \"bible -f --tag comfort\"\n\n" +
+		" See what you have read with \"bible history\" (--since 2026-10-01, --since 7d, --until yesterday), and 'l' (last) when -i starts carries on from the last verse\n\n" +
+		" See how much of the bible you have read, your streak and the chapters you haven't read yet with \"bible stats\"\n\n" +
+		" For scripts, print verses as json, jsonl, csv or md with --format, ie \"bible --format json John 3\"\n\n" +
 		"Available arguments:\n"
 		fmt.Fprintf(w, description, os.Args[0])
 		flag.PrintDefaults()
 		//fmt.Fprintf(w, "...custom postamble ... \n")
 	}	
 
-	flag.Parse()
+	args := parseFlags()
 
-	db, err := sql.Open("sqlite3", tmpFile.Name())
+	// The embedded KJV is always there. Anything else comes from the translations folder
+	f.RegisterTranslation(f.Translation{
+		Name: "KJV",
+		Description: "King James Version (built in)",
+		Path: dbPath,
+		Embedded: true,
+	})
+
+	if err := f.SetOutputFormat(*format); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	// json needs to print everything at the end, so make sure that happens. os.Exit skips the defers,
+	// so anything that needs to exit with an error after printing sets exitCode instead
+	exitCode := 0
+	defer func() {
+		f.FlushOutput()
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	if *translation == "list" {
+		f.ListTranslations()
+		return
+	}
+
+	db, err := f.OpenTranslation(*translation)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer f.CloseDatabase(db)
+
+	// Open all the translations to compare, if there are any
+	var texts []f.ParallelText
+	if *compare != "" {
+		texts, err = f.OpenParallelTexts(*compare)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer f.CloseParallelTexts(texts)
 	}
-	defer db.Close()
 
 	// These are all the different "modes"
 	switch {
+	case len(args) > 0 && subcommands[args[0]]:
+		runSubcommand(db, args[0], args[1:])
+	case *tui:
+		tuiMode(db, args)
 	case *interactive:
-		interactiveMode(db)
+		interactiveMode(db, texts, 0)
 	case *list:
-		listMode(db)
+		if !listMode(db, args) {
+			exitCode = 1
+		}
 	case *version:
 		fmt.Println(versionNumber)
 	case *random:
-		printRandomVerse(db)
+		printRandomVerse(db, texts)
 	case *search:
-		searchForTerm(db, *exact)
+		if *in == "list" {
+			f.ListBookGroups()
+			return
+		}
+		if !searchForTerm(client.New(db), args, f.SearchOptions{Exact: *exact, Rank: *rank, In: *in, Limit: *limit, Offset: *offset, Regex: *regex}, *count) {
+			exitCode = 1
+		}
 	//case *test:
 		//testFunction(db)
 	case *favorite:
-		favoriteMode(db)
+		favoriteMode(db, *tag)
+	default:
+		if !singleShotMode(db, texts, args) {
+			exitCode = 1
+		}
+	}
+}
+
+
+// Writes the embedded database to a temporary file. This is only used if it can't go in the cache folder
+func extractTempDatabase() (string, error) {
+	tmpFile, err := os.CreateTemp("", "kjv.db")
+	if err != nil {
+		return "", err
+	}
+
+	// Write the embedded database to the temporary file
+	if _, err := tmpFile.Write(embeddedDb); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+
+	return tmpFile.Name(), nil
+}
+
+
+// All the subcommands, ie "bible serve". These have their own flags, see runSubcommand
+var subcommands = map[string]bool{
+	"serve": true,
+	"plan": true,
+	"notes": true,
+	"highlights": true,
+	"bookmark": true,
+	"favorite": true,
+	"history": true,
+	"stats": true,
+}
+
+
+// Runs a subcommand with the rest of the arguments after it
+func runSubcommand(db *sql.DB, name string, args []string) {
+	switch name {
+	case "serve":
+		serveMode(db, args)
+	case "plan":
+		planMode(db, args)
+	case "notes":
+		notesMode(db, args)
+	case "highlights":
+		highlightsMode(db, args)
+	case "bookmark":
+		bookmarkMode(db, args)
+	case "favorite":
+		favoriteCommand(db, args)
+	case "history":
+		historyMode(db, args)
+	case "stats":
+		statsMode(db, args)
+	}
+}
+
+
+// Starts the http server so other programs can look things up without running bible every time.
+// ie "bible serve --addr :8080" then "curl localhost:8080/passage?ref=John+3:16"
+func serveMode(db *sql.DB, args []string) {
+	serveFlags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := serveFlags.String("addr", "localhost:8080", "Address to listen on, ie :8080 for every interface")
+	serveFlags.Parse(args)
+
+	if err := f.Serve(db, *addr); err != nil {
+		fmt.Println("Error running server: ", err)
+		os.Exit(1)
+	}
+}
+
+
+// Reading plans. "bible plan list", "bible plan start year", "bible plan today", "bible plan done" and "bible plan status"
+func planMode(db *sql.DB, args []string) {
+	planFlags := flag.NewFlagSet("plan", flag.ExitOnError)
+	date := planFlags.String("date", "", "Use this date instead of today, ie 2024-01-01 (for start, today and done)")
+	read := planFlags.Bool("read", false, "Print the verses for the day too, not just what to read (for today)")
+	planFlags.Usage = func() {
+		w := planFlags.Output()
+		fmt.Fprintf(w, "Usage: %s plan <command> [flags]\n\n", os.Args[0])
+		fmt.Fprintf(w, " list ............ show all the reading plans\n")
+		fmt.Fprintf(w, " start <plan> .... start a plan (from today, or --date)\n")
+		fmt.Fprintf(w, " today ........... what to read today\n")
+		fmt.Fprintf(w, " done [day] ...... mark today (or that day) as read\n")
+		fmt.Fprintf(w, " status .......... how far through the plan you are\n\n")
+		fmt.Fprintf(w, "Custom plans (json or yaml) go in %s\n\n", f.GetPlansDir())
+		planFlags.PrintDefaults()
+	}
+
+	planArgs := parseSubcommandFlags(planFlags, args)
+
+	if len(planArgs) == 0 {
+		planFlags.Usage()
+		return
+	}
+
+	now := time.Now()
+	if *date != "" {
+		var err error
+		now, err = time.ParseInLocation("2006-01-02", *date, time.Local)
+		if err != nil {
+			fmt.Printf("Bad date \"%s\", it should look like 2024-01-01\n", *date)
+			return
+		}
+	}
+
+	var progress f.PlanProgress
+	if err := progress.Load(); err != nil {
+		fmt.Println("Error loading plan progress: ", err)
+		return
+	}
+
+	switch planArgs[0] {
+	case "list":
+		plans, err := f.ReadingPlans(db)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, plan := range plans {
+			current := " "
+			if plan.Name == progress.Plan {
+				current = "*"
+			}
+			fmt.Printf("%s %-14s %3d days  %s\n", current, plan.Name, len(plan.Days), plan.Description)
+		}
+
+	case "start":
+		if len(planArgs) < 2 {
+			fmt.Println("Which plan? ie bible plan start year (bible plan list to see them)")
+			return
+		}
+		plan, err := f.FindReadingPlan(db, planArgs[1])
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if progress.Plan != "" && len(progress.Done) > 0 {
+			fmt.Printf("Replacing plan %s (%d days read)\n", progress.Plan, len(progress.Done))
+		}
+		progress = f.PlanProgress{Plan: plan.Name, Start: now.Format("2006-01-02")}
+		err = f.UpdateUserData(func(ut *f.UserTx) error {
+			return ut.StartPlan(progress.Plan, progress.Start)
+		})
+		if err != nil {
+			fmt.Println("Error saving plan progress: ", err)
+			return
+		}
+		fmt.Printf("Started %s on %s, %d days\n", plan.Name, progress.Start, len(plan.Days))
+
+	case "today", "done", "status":
+		if progress.Plan == "" {
+			fmt.Println("You haven't started a plan yet, ie bible plan start year (bible plan list to see them)")
+			return
+		}
+		plan, err := f.FindReadingPlan(db, progress.Plan)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		day, err := progress.DayNumber(now)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		switch planArgs[0] {
+		case "today":
+			printPlanDay(db, plan, progress, day, *read)
+		case "done":
+			if len(planArgs) > 1 {
+				day, err = strconv.Atoi(planArgs[1])
+				if err != nil {
+					fmt.Printf("Not a day number: %s\n", planArgs[1])
+					return
+				}
+			}
+			if err := progress.MarkDone(db, plan, day, now); err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("Day %d of %s done (%d of %d days read)\n", day, plan.Name, len(progress.Done), len(plan.Days))
+		case "status":
+			printPlanStatus(db, plan, progress, day)
+		}
+
+	default:
+		fmt.Printf("Unknown plan command \"%s\"\n\n", planArgs[0])
+		planFlags.Usage()
+	}
+}
+
+
+// Prints what to read on a day of a plan. If read is true it prints the verses too
+func printPlanDay(db *sql.DB, plan f.ReadingPlan, progress f.PlanProgress, day int, read bool) {
+	if day < 1 {
+		fmt.Printf("%s doesn't start until %s\n", plan.Name, progress.Start)
+		return
+	}
+	if day > len(plan.Days) {
+		fmt.Printf("%s finished on day %d. ", plan.Name, len(plan.Days))
+		printPlanStatus(db, plan, progress, day)
+		return
+	}
+
+	status := ""
+	if progress.IsDone(day) {
+		status = " (done)"
+	}
+	fmt.Printf("%s, day %d of %d%s\n", plan.Name, day, len(plan.Days), status)
+
+	reading := plan.Days[day-1]
+	if strings.TrimSpace(reading) == "" {
+		fmt.Println("Nothing to read today, it's a catch up day")
+	} else {
+		f.WordWrap(reading)
+	}
+
+	// Any days before today that haven't been read yet. Days in a row get shown as a range, ie 3-7
+	var behind []string
+	count := 0
+	for d := 1; d < day; d++ {
+		if progress.IsDone(d) || strings.TrimSpace(plan.Days[d-1]) == "" {
+			continue
+		}
+		end := d
+		for end+1 < day && !progress.IsDone(end+1) {
+			end++
+		}
+		if end == d {
+			behind = append(behind, strconv.Itoa(d))
+		} else {
+			behind = append(behind, fmt.Sprintf("%d-%d", d, end))
+		}
+		count += end - d + 1
+		d = end
+	}
+	if len(behind) > 0 {
+		fmt.Println()
+		f.WordWrap(fmt.Sprintf("Behind by %d days: %s (bible plan done <day> to mark one)", count, strings.Join(behind, ", ")))
+	}
+
+	if read && strings.TrimSpace(reading) != "" {
+		fmt.Println()
+		singleShotMode(db, nil, []string{reading})
+	}
+}
+
+
+// Prints how far through the plan you are
+func printPlanStatus(db *sql.DB, plan f.ReadingPlan, progress f.PlanProgress, day int) {
+	// How many verses the whole plan covers, so the percent is by verses and not days
+	total := 0
+	for _, reading := range plan.Days {
+		ranges, err := f.ReadingIdRanges(db, reading)
+		if err != nil {
+			continue
+		}
+		for _, r := range ranges {
+			total += r[1] - r[0] + 1
+		}
+	}
+
+	percent := 0.0
+	if total > 0 {
+		percent = float64(progress.VersesRead()) * 100 / float64(total)
+	}
+
+	fmt.Printf("%s, started %s\n", plan.Name, progress.Start)
+	fmt.Printf("Today is day %d of %d\n", day, len(plan.Days))
+	fmt.Printf("%d days read, %.1f%% of the plan\n", len(progress.Done), percent)
+}
+
+
+// Notes. "bible notes" lists them, "bible notes --search grace" searches them,
+// "bible notes add John 3:16-18" adds or edits one in $EDITOR and "bible notes rm John 3:16-18" removes one
+func notesMode(db *sql.DB, args []string) {
+	notesFlags := flag.NewFlagSet("notes", flag.ExitOnError)
+	search := notesFlags.String("search", "", "Only show notes with these words in them")
+	notesFlags.Parse(args)
+	args = notesFlags.Args()
+
+	if len(args) == 0 {
+		f.ListNotes(*search)
+		return
+	}
+
+	start, end, err := noteRange(db, strings.Join(args[1:], " "))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	switch args[0] {
+	case "add", "edit":
+		f.EditNote(db, start, end)
+	case "rm", "remove":
+		err := f.UpdateUserData(func(ut *f.UserTx) error {
+			removed, err := ut.RemoveNote(start, end)
+			if err == nil && !removed {
+				err = fmt.Errorf("There isn't a note on %s", f.IdRangeString(db, start, end))
+			}
+			return err
+		})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Removed note on %s\n", f.IdRangeString(db, start, end))
+	default:
+		fmt.Printf("Unknown notes command \"%s\", use add, rm or --search\n", args[0])
+	}
+}
+
+
+// Lists highlighted verses. "bible highlights" for all of them, "bible highlights --category promise" for one category
+func highlightsMode(db *sql.DB, args []string) {
+	highlightsFlags := flag.NewFlagSet("highlights", flag.ExitOnError)
+	category := highlightsFlags.String("category", "", "Only show verses highlighted in this category, ie promise, command or prophecy")
+	highlightsFlags.Parse(args)
+
+	f.ListHighlights(db, *category)
+}
+
+
+// What has been read. "bible history" for the last 20 things, "bible history --since 7d" for the last week,
+// "bible history --since 2026-10-01 --until 2026-10-07 --all" for a week without a limit
+func historyMode(db *sql.DB, args []string) {
+	historyFlags := flag.NewFlagSet("history", flag.ExitOnError)
+	since := historyFlags.String("since", "", "Only show reading from this day on, ie 2026-10-01, yesterday or 7d")
+	until := historyFlags.String("until", "", "Only show reading up to and including this day")
+	limit := historyFlags.Int("limit", 20, "How many to show (the newest ones)")
+	all := historyFlags.Bool("all", false, "Show everything, not just the newest --limit")
+	format := historyFlags.String("format", "", "Print as json or jsonl instead of text")
+	historyFlags.Parse(args)
+
+	if *format != "" {
+		if err := f.SetOutputFormat(*format); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	var sinceTime, untilTime time.Time
+	var err error
+	if *since != "" {
+		if sinceTime, err = f.ParseHistoryDate(*since); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if *until != "" {
+		if untilTime, err = f.ParseHistoryDate(*until); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		// Up to the end of that day
+		untilTime = untilTime.AddDate(0, 0, 1)
+	}
+
+	entries, err := f.LoadHistory(sinceTime, untilTime, 0)
+	if err != nil {
+		fmt.Println("Error loading history:", err)
+		os.Exit(1)
+	}
+	entries = f.GroupHistory(db, entries)
+	if !*all && *limit > 0 && len(entries) > *limit {
+		entries = entries[:*limit]
+	}
+
+	switch f.GetOutputFormat() {
+	case "text":
+		f.PrintHistory(entries)
+	case "json", "jsonl":
+		if entries == nil {
+			entries = []f.HistoryEntry{}
+		}
+		f.EmitJSON(entries)
+	default:
+		fmt.Println("The history can only be printed as text, json or jsonl")
+		os.Exit(1)
+	}
+}
+
+
+// How much has been read. "bible stats", or "bible stats --format json" for scripts
+func statsMode(db *sql.DB, args []string) {
+	statsFlags := flag.NewFlagSet("stats", flag.ExitOnError)
+	format := statsFlags.String("format", "", "Print as json or jsonl instead of text")
+	statsFlags.Parse(args)
+
+	if *format != "" {
+		if err := f.SetOutputFormat(*format); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	stats, err := f.GetReadingStats(db)
+	if err != nil {
+		fmt.Println("Error working out the stats:", err)
+		os.Exit(1)
+	}
+
+	switch f.GetOutputFormat() {
+	case "text":
+		f.PrintStats(stats)
+	case "json", "jsonl":
+		f.EmitJSON(stats)
+	default:
+		fmt.Println("The stats can only be printed as text, json or jsonl")
+		os.Exit(1)
+	}
+}
+
+
+// Named bookmarks. "bible bookmark list", "bible bookmark set devotions John 3:16", "bible bookmark go devotions"
+// (opens interactive mode there) and "bible bookmark rm devotions"
+func bookmarkMode(db *sql.DB, args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		saveData, err := f.LoadSaveData()
+		if err != nil {
+			fmt.Println("Error loading data:", err)
+			return
+		}
+		bookmarks := saveData.AllBookmarks()
+		if len(bookmarks) == 0 {
+			fmt.Println("No bookmarks yet, ie bible bookmark set devotions John 3:16 (or 'b' in interactive mode)")
+			return
+		}
+		f.PrintBookmarks(db, bookmarks)
+		return
+	}
+
+	if len(args) < 2 {
+		fmt.Printf("Which bookmark? ie bible bookmark %s devotions\n", args[0])
+		return
+	}
+	name := args[1]
+
+	switch args[0] {
+	case "set":
+		if len(args) < 3 {
+			fmt.Printf("Where should %s go? ie bible bookmark set %s John 3:16\n", name, name)
+			return
+		}
+		id := f.ParseInteractiveCommand(db, args[2:])
+		if id == -1 {
+			return
+		}
+		err := f.UpdateUserData(func(ut *f.UserTx) error {
+			return ut.SetBookmark(name, id)
+		})
+		if err != nil {
+			fmt.Println("Error saving data:", err)
+			return
+		}
+		fmt.Printf("Bookmark %s is at %s\n", name, f.IdRangeString(db, id, id))
+	case "go":
+		saveData, err := f.LoadSaveData()
+		if err != nil {
+			fmt.Println("Error loading data:", err)
+			return
+		}
+		id, ok := saveData.GetBookmark(name)
+		if !ok {
+			fmt.Printf("There isn't a bookmark called %s (bible bookmark list to see them)\n", name)
+			return
+		}
+		interactiveMode(db, nil, id)
+	case "rm", "remove":
+		err := f.UpdateUserData(func(ut *f.UserTx) error {
+			removed, err := ut.RemoveBookmark(name)
+			if err == nil && !removed {
+				err = fmt.Errorf("There isn't a bookmark called %s", name)
+			}
+			return err
+		})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("Removed bookmark %s\n", name)
 	default:
-		singleShotMode(db)
+		fmt.Printf("Unknown bookmark command \"%s\", use list, set, go or rm\n", args[0])
 	}
 }
 
 
+// Changes favorites without the interactive prompt.
+// "bible favorite add John 3:16 --tag comfort --note 'for hard days'", "bible favorite rm John 3:16",
+// "bible favorite rm John 3:16 --tag comfort" (only out of that collection), "bible favorite move John 3:16 --from comfort --to memory-2026"
+// and "bible favorite tags". A range (ie John 3:16-18) does every verse in it.
+func favoriteCommand(db *sql.DB, args []string) {
+	favoriteFlags := flag.NewFlagSet("favorite", flag.ExitOnError)
+	tag := favoriteFlags.String("tag", "", "Tag (collection) to add to or remove from, ie comfort")
+	note := favoriteFlags.String("note", "", "A note to go with the favorite (add)")
+	from := favoriteFlags.String("from", "", "Tag to move out of (move)")
+	to := favoriteFlags.String("to", "", "Tag to move into (move)")
+	favoriteArgs := parseSubcommandFlags(favoriteFlags, args)
+
+	if len(favoriteArgs) == 0 {
+		fmt.Println("Use add, rm, move or tags, ie bible favorite add John 3:16 --tag comfort")
+		return
+	}
+
+	if favoriteArgs[0] == "tags" {
+		f.ListFavoriteTags()
+		return
+	}
+
+	start, end, err := noteRange(db, strings.Join(favoriteArgs[1:], " "))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	reference := f.IdRangeString(db, start, end)
+
+	// What to change, and what to say after it has been saved
+	var update func(ut *f.UserTx) error
+	var message string
+
+	switch favoriteArgs[0] {
+	case "add":
+		update = func(ut *f.UserTx) error {
+			for id := start; id <= end; id++ {
+				if err := ut.TagFavorite(id, *tag); err != nil {
+					return err
+				}
+				if *note != "" {
+					if err := ut.SetFavoriteNote(id, *note); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+		message = fmt.Sprintf("Added %s to favorites", reference)
+	case "rm", "remove":
+		update = func(ut *f.UserTx) error {
+			for id := start; id <= end; id++ {
+				var err error
+				if *tag != "" {
+					_, err = ut.UntagFavorite(id, *tag)
+				} else {
+					err = ut.RemoveFavorite(id)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if *tag != "" {
+			message = fmt.Sprintf("Removed %s from %s", reference, *tag)
+		} else {
+			message = fmt.Sprintf("Removed %s from favorites", reference)
+		}
+	case "move":
+		if *from == "" || *to == "" {
+			fmt.Println("Move needs --from and --to, ie bible favorite move John 3:16 --from comfort --to memory-2026")
+			return
+		}
+		update = func(ut *f.UserTx) error {
+			for id := start; id <= end; id++ {
+				if _, err := ut.UntagFavorite(id, *from); err != nil {
+					return err
+				}
+				if err := ut.TagFavorite(id, *to); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		message = fmt.Sprintf("Moved %s from %s to %s", reference, *from, *to)
+	default:
+		fmt.Printf("Unknown favorite command \"%s\", use add, rm, move or tags\n", favoriteArgs[0])
+		return
+	}
+
+	err = f.UpdateUserData(update)
+	if err != nil {
+		fmt.Println("Error saving data:", err)
+		return
+	}
+	fmt.Println(message)
+}
+
+
+// Parses the flags for a subcommand. Same as the main flags, they can go anywhere. It gives back everything that wasn't a flag
+func parseSubcommandFlags(flags *flag.FlagSet, args []string) []string {
+	var rest []string
+	for {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+	return rest
+}
+
+
+// Works out the first and last verse id of a reference for a note. Only one range, ie "John 3:16-18" and not "John 3:16; 4:1"
+func noteRange(db *sql.DB, reference string) (int, int, error) {
+	ref, err := f.ParseReference(reference)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(ref.Ranges) > 1 {
+		return 0, 0, fmt.Errorf("A note can only go on one verse or range, ie John 3:16-18")
+	}
+	return f.GetIdRange(db, ref.Ranges[0])
+}
+
+
+// The flag package stops at the first thing that isn't a flag, so "bible John 3 16 -t WEB" wouldn't see the -t.
+// This keeps going after each argument so the flags can go anywhere. It gives back everything that wasn't a flag.
+//
+// If the first thing that isn't a flag is a subcommand (ie "bible serve --addr :8080"), it stops there,
+// because everything after it belongs to the subcommand and its own flags.
+func parseFlags() []string {
+	var args []string
+	rest := os.Args[1:]
+	for {
+		flag.CommandLine.Parse(rest)
+		rest = flag.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if len(args) == 0 && subcommands[rest[0]] {
+			return rest
+		}
+		args = append(args, rest[0])
+		rest = rest[1:]
+	}
+	return args
+}
+
+
 // This is the main interactive mode that opens up a "command line" that you can interact with and change verses.
-func interactiveMode(db *sql.DB) {
-	var bookName string
-	var chapter string
-	var verse string
-	var id int
+// texts are the translations to show side by side when compare is turned on (with 'c'). It can be nil.
+// startId is the verse to start at (ie from "bible bookmark go"). If it's 0 it asks where to start.
+func interactiveMode(db *sql.DB, texts []f.ParallelText, startId int) {
+	id := startId
+
+	// Start off showing the translations side by side if they were given with --compare
+	parallel := len(texts) > 0
+
+	// Translations opened with 'c' get closed when it's turned off again. The ones from --compare are closed by main
+	openedHere := false
+	defer func() {
+		if openedHere {
+			f.CloseParallelTexts(texts)
+		}
+	}()
+
+	// One verse at a time, a chapter at a time, or a screen of paragraph ('v' changes it).
+	// pageStart and pageEnd are the first and last verse shown. n needs to know where the next chapter or screen starts,
+	// and they go in the history
+	mode := "verse"
+	pageStart, pageEnd := id, id
+
+	// Tab completes books, chapters and verses at the prompt
+	f.EnableCompletion(db)
+
+	// Let them know they can carry on from last time
+	if last, ok := f.LastReading(); ok && id == 0 {
+		fmt.Printf("Last time you were reading %s, enter 'l' (last) to carry on from there\n", last.Reference)
+	}
 
 	// Loop to get initial input from user. 
-	for {
+	for id == 0 {
 		// Get user input 
 		userInputSplit := f.GetUserInput("Enter Book Chapter Name(ie Genesis 1 1): ")
 
@@ -151,8 +893,15 @@ func interactiveMode(db *sql.DB) {
 			break
 		// Load bookmark
 		} else if len(userInputSplit) == 1 && userInputSplit[0] == "b" {
-			id = f.LoadBookmark()
+			id = f.LoadBookmark(db, 0)
 			break
+		// Carry on from the last thing read
+		} else if len(userInputSplit) == 1 && (userInputSplit[0] == "l" || userInputSplit[0] == "last") {
+			if last, ok := f.LastReading(); ok {
+				id = last.Start
+				break
+			}
+			fmt.Println("Nothing has been read yet")
 		} else if len(userInputSplit) == 1 && userInputSplit[0] == "q" {
 			return
 		} else if len(userInputSplit) == 1 && userInputSplit[0] == "?" {
@@ -160,14 +909,15 @@ func interactiveMode(db *sql.DB) {
 		} else if len(userInputSplit) == 1 && userInputSplit[0] == "h" {
 			f.PrintInteractiveHelp()
 		// If any other single character, prompt proper usage
-		} else if len(userInputSplit) == 1 {
-			f.WordWrap("Please enter either a book chapter verse(ie Genesis 1 1) or 'r' for random verse")
+		} else if len(userInputSplit) == 1 && len(userInputSplit[0]) <= 1 {
+			f.WordWrap("Please enter either a book chapter verse(ie Genesis 1 1 or John 3:16) or 'r' for random verse")
 		// If Specific book chapter verse to start at, get the id
 		} else {
 			id = f.ParseInteractiveCommand(db, userInputSplit)
 			// Check if not valid input ParseInteractiveCommand returns -1 on failure.
 			if id == -1 {
-				fmt.Println("Please enter a valid verse\n")
+				fmt.Println("Please enter a valid verse")
+				fmt.Println()
 			} else {
 				break
 			}
@@ -183,31 +933,104 @@ func interactiveMode(db *sql.DB) {
 		var bibleVerse Bible
 		err := db.QueryRow("SELECT id, bookName, chapter, verse, text FROM bible WHERE id = ?", id).Scan(&bibleVerse.ID, &bibleVerse.BookName, &bibleVerse.Chapter, &bibleVerse.Verse, &bibleVerse.Text)
 		if err != nil {
-			fmt.Printf("Verse %s %d:%d not found.\n", bookName, chapter, verse)
+			fmt.Printf("Verse %d not found.\n", id)
 			break
 		}
 
-		// This actually prints the verse
-		fmt.Printf("%s %d:%d\n", bibleVerse.BookName, bibleVerse.Chapter, bibleVerse.Verse)
-		f.WordWrap(bibleVerse.Text)
+		// This actually prints the verse (or chapter, or paragraph)
+		switch {
+		case mode == "chapter" && parallel:
+			if err := f.PrintParallelRange(texts, f.VerseRange{Book: bibleVerse.BookName, Chapter: bibleVerse.Chapter, EndChapter: bibleVerse.Chapter}); err != nil {
+				fmt.Printf("%s\n\n", err)
+			}
+			pageStart, pageEnd, _ = f.ChapterIdRange(db, id)
+		case mode == "chapter":
+			pageStart, pageEnd = f.PrintChapter(db, id)
+		case mode == "paragraph":
+			pageStart = id
+			pageEnd = f.PrintParagraph(db, id)
+		case parallel:
+			f.PrintParallelVerse(texts, f.Bible{BookName: bibleVerse.BookName, Chapter: bibleVerse.Chapter, Verse: bibleVerse.Verse})
+			f.PrintVerseNotes(bibleVerse.ID)
+			pageStart, pageEnd = id, id
+		default:
+			f.PrintVerseText(f.Bible(bibleVerse))
+			// Any notes on this verse
+			f.PrintVerseNotes(bibleVerse.ID)
+			pageStart, pageEnd = id, id
+		}
+		f.LogReading(db, pageStart, pageEnd, "interactive")
 		
 		// Prompt for next command
 		inputSplit := f.GetUserInput(": ")
 
 		if len(inputSplit) == 1 {
 			switch strings.ToLower(inputSplit[0]) {
-			case "n": // Go to next verse
+			case "n": // Go to next verse (or chapter, or screen)
+				if mode == "verse" {
 					id++
-			case "p": // Go to prev verse
-				if id > 1 {
-					id--
 				} else {
+					id = pageEnd + 1
+				}
+			case "p": // Go to prev verse (or chapter, or screen)
+				switch {
+				case id <= 1:
 					fmt.Println("You are at the first verse.")
+				case mode == "chapter":
+					// The start of this chapter, then the start of the one before it
+					start, _, err := f.ChapterIdRange(db, id)
+					if err == nil && start > 1 {
+						start, _, err = f.ChapterIdRange(db, start-1)
+					}
+					if err != nil {
+						fmt.Println(err)
+						continue
+					}
+					id = start
+				case mode == "paragraph":
+					id = f.PreviousParagraph(db, id)
+				default:
+					id--
 				}
+			case "v": // Change how the verses are shown
+				mode = f.NextDisplayMode(mode)
+				fmt.Printf("Showing a %s at a time\n", mode)
 			case "b":
-				id = f.BookMark(bibleVerse.ID)
+				id = f.BookMark(db, bibleVerse.ID)
 			case "f":
 				f.Favorites(db, bibleVerse.ID)
+			case "m": // Highlight (mark) this verse in a category
+				f.Highlights(db, bibleVerse.ID)
+			case "a": // Add or edit a note on this verse
+				f.EditNote(db, bibleVerse.ID, bibleVerse.ID)
+			case "t": // Full screen reader, then carry on from wherever it was left
+				newId, err := f.FullScreenReader(db, id)
+				if err != nil {
+					fmt.Println(err)
+				}
+				id = newId
+			case "c": // Turn side by side translations on or off
+				if parallel {
+					parallel = false
+					if openedHere {
+						f.CloseParallelTexts(texts)
+						texts = nil
+						openedHere = false
+					}
+				} else {
+					// Nothing to compare yet, so ask which ones
+					if len(texts) == 0 {
+						compareSplit := f.GetUserInput("Compare which translations? (ie KJV,WEB): ")
+						opened, err := f.OpenParallelTexts(strings.Join(compareSplit, ","))
+						if err != nil {
+							fmt.Println(err)
+							continue
+						}
+						texts = opened
+						openedHere = true
+					}
+					parallel = true
+				}
 			case "?":
 				f.PrintInteractiveHelp()
 			case "h":
@@ -223,6 +1046,23 @@ func interactiveMode(db *sql.DB) {
 			default:
 				fmt.Println("Invalid input. Please enter 'n', 'p', 'r' or 'q'.")
 			}
+		// Straight to a display mode, ie "v paragraph"
+		} else if strings.ToLower(inputSplit[0]) == "v" {
+			newMode, err := f.ParseDisplayMode(inputSplit[1])
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			mode = newMode
+			fmt.Printf("Showing a %s at a time\n", mode)
+		// A note on a range, ie "a John 3:16-18"
+		} else if strings.ToLower(inputSplit[0]) == "a" {
+			start, end, err := noteRange(db, strings.Join(inputSplit[1:], " "))
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			f.EditNote(db, start, end)
 		} else {
 			// Capture current id incase of failure
 			oldid := id
@@ -241,10 +1081,53 @@ func interactiveMode(db *sql.DB) {
 }
 
 
+// The full screen reader (--tui). It starts at the reference if there is one, otherwise at the bookmark, otherwise Genesis 1:1
+func tuiMode(db *sql.DB, args []string) {
+	id := 1
+	if len(args) > 0 {
+		ref, err := f.ParseReference(strings.Join(args, " "))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		id, _, err = f.GetIdRange(db, ref.Ranges[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else if saveData, err := f.LoadSaveData(); err == nil && saveData.Bookmark != 0 {
+		id = saveData.Bookmark
+	}
+
+	if _, err := f.FullScreenReader(db, id); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+
 // This is just to give info. If no other arguments, list all books. If only book, give number of chapters. If book and chapter, give number of verses.
-func listMode(db *sql.DB) {
+// With --format json/jsonl it prints the same things as json for scripts. It gives back false if something couldn't be found
+func listMode(db *sql.DB, args []string) bool {
+	switch f.GetOutputFormat() {
+	case "text", "json", "jsonl":
+	default:
+		f.PrintMessage("The books and chapters can only be listed as text, json or jsonl\n")
+		return false
+	}
+
 	// Print all books
-	if len(os.Args) == 2 {
+	if len(args) == 0 {
+		if !f.IsTextOutput() {
+			books, err := client.New(db).Books()
+			if err != nil {
+				f.PrintMessage("%s\n", err)
+				return false
+			}
+			f.EmitJSON(books)
+			return true
+		}
+
 		var allBooksString string
 		for i := 0; i < len(allBooks); i++ {
 			// This is just for formatting. No comma and newline on last one
@@ -261,252 +1144,232 @@ func listMode(db *sql.DB) {
 		f.WordWrap(allBooksString)	
 	
 	// If just a book is provided, print Number of chapters
-	} else if len(os.Args) == 3 {
-		var passage Passage
-		passage.BookName = os.Args[2]
+	} else if len(args) == 1 {
+		chapters, err := client.New(db).Chapters(args[0])
+		if err != nil {
+			f.PrintMessage("%s\n", err)
+			return false
+		}
+		if !f.IsTextOutput() {
+			f.EmitJSON(chapters)
+			return true
+		}
 
-		chapters := f.GetAllChaptersInBook(db, passage.BookName)
-		fmt.Printf("Chapters in %s: %d\n", passage.BookName, chapters)
+		book, _ := f.ResolveBook(args[0])
+		fmt.Printf("Chapters in %s: %d\n", book, len(chapters))
 
 	// if a book and a chapter, print number of verses
-	} else if len(os.Args) == 4 {
-		var passage Passage 
-		passage.BookName = os.Args[2]
-		passage.Chapter = os.Args[3]
+	} else if len(args) == 2 {
+		chapters, err := client.New(db).Chapters(args[0])
+		if err != nil {
+			f.PrintMessage("%s\n", err)
+			return false
+		}
 
-		verses := f.GetAllVersesInChapter(db, passage.BookName, passage.Chapter)
-		fmt.Printf("Verses in %s %s: %d\n", passage.BookName, passage.Chapter, verses)
+		book, _ := f.ResolveBook(args[0])
+		for _, chapter := range chapters {
+			if strconv.Itoa(chapter.Chapter) == args[1] {
+				if !f.IsTextOutput() {
+					f.EmitJSON(chapter)
+					return true
+				}
+				fmt.Printf("Verses in %s %s: %d\n", book, args[1], chapter.Verses)
+				return true
+			}
+		}
+		f.PrintMessage("Can't find chapter %s in book \"%s\"\n", args[1], book)
+		return false
 	}
+	return true
 }
 
 
 // Fucntion to print a random verse. use -r on command line
-func printRandomVerse(db *sql.DB) {
+func printRandomVerse(db *sql.DB, texts []f.ParallelText) {
 	// Get random verse
-	passage := f.RandomVerse(db)
-	// Print random verse
-	f.PrintVerse(db, passage.BookName, passage.Chapter, passage.Verse)
-}
-
-
+	verse, err := client.New(db).Random()
+	if err != nil {
+		fmt.Println("Can't get a random verse: ", err)
+		return
+	}
 
-// Search for a term or an exact term
-func searchForTerm(db *sql.DB, exact bool) {
-	// This executes an exact search for the search term
-	if exact {
-		query := "SELECT bookName, chapter, verse, text FROM bible WHERE text LIKE ?"
-		rows, err := db.Query(query, "% "+os.Args[3]+" %")
-		if err != nil {
-			fmt.Println("Error in query of exact search: ", err)
-			return
-		}
+	f.LogReading(db, verse.ID, verse.ID, "random")
 
-    	defer rows.Close()
+	// Print random verse, side by side if --compare was used
+	if len(texts) > 0 {
+		f.PrintParallelVerse(texts, verse)
+		return
+	}
+	f.EmitVerse(verse)
+}
 
-		if !rows.Next() {
-			fmt.Println("No search found matching: ", os.Args[3])
-			return
-		}
 
-		for {
-			var bible Bible
-			err = rows.Scan(&bible.BookName, &bible.Chapter, &bible.Verse, &bible.Text)
-			if err != nil {
-				fmt.Println("Error reading row(exact search): ", err)
-				return
-			}
 
-			fmt.Printf("%s %d:%d\n", bible.BookName, bible.Chapter, bible.Verse)
-			f.WordWrap(bible.Text)
-			fmt.Printf("\n")
+// Search for a term or an exact term. If countOnly is true (--count) it just prints how many matches there are in each book.
+// It gives back false if the search couldn't be done
+func searchForTerm(b *client.Bible, args []string, opts f.SearchOptions, countOnly bool) bool {
+	if len(args) == 0 {
+		f.PrintMessage("Please enter something to search for, ie bible -s love\n")
+		return false
+	}
 
-			if !rows.Next() {
-				break
-			}
-		}
-
-	// This executes a search that takes any match, ie love with match with loved
-	} else {
-		query := "SELECT bookName, chapter, verse, text FROm bible WHERE text LIKE ?"
-		// This is the only thing that is different from exact search. No spaces around the search term
-		rows, err := db.Query(query, "%"+os.Args[2]+"%")
-		if err != nil {
-			fmt.Println("Error in query of search: ", err)
-			return
+	// The counts aren't verses, so they can't go in a csv or markdown of verses
+	if countOnly {
+		switch f.GetOutputFormat() {
+		case "text", "json", "jsonl":
+		default:
+			f.PrintMessage("--count can only be printed as text, json or jsonl\n")
+			return false
 		}
+	}
 
-    	defer rows.Close()
+	// Every word is part of the search, ie "bible -s love mercy" is love AND mercy
+	term := strings.Join(args, " ")
 
-		if !rows.Next() {
-			fmt.Println("No search found matching: ", os.Args[2])
-			return
-		}
+	// This is just for highlighting the matches. BuildSearchQuery checks for errors in the search
+	highlight := f.SearchHighlighter(term, opts)
 
-		for {
-			var bible Bible
-			err = rows.Scan(&bible.BookName, &bible.Chapter, &bible.Verse, &bible.Text)
-			if err != nil {
-				fmt.Println("Error reading row(search): ", err)
-				return
-			}
+	// How many matches in each book. This is printed at the end, or on its own with --count
+	counts, err := b.SearchCounts(term, opts)
+	if err != nil {
+		f.PrintMessage("Error building search: %s\n", err)
+		return false
+	}
 
-			fmt.Printf("%s %d:%d\n", bible.BookName, bible.Chapter, bible.Verse)
-			f.WordWrap(bible.Text)
-			fmt.Printf("\n")
+	total := 0
+	for _, count := range counts {
+		total += count.Count
+	}
 
-			if !rows.Next() {
-				break
-			}
+	if total == 0 {
+		// For json/csv/etc no results is just an empty list, which FlushOutput takes care of
+		if f.IsTextOutput() {
+			fmt.Println("No search found matching: ", term)
 		}
+		return true
 	}
-}
 
-func favoriteMode(db *sql.DB) {
-	f.ListFavorites(db)
-}
-
-// This runs if no "flags" are provided, but there may be arguments. 
-func singleShotMode(db *sql.DB) {
-	// if no argurments provided, print all books
-	if len(os.Args) == 1 {
-	var allBooksString string
-		for i := 0; i < len(allBooks); i++ {
-			// This is just for formatting. No comma and newline on last one
-			if i == len(allBooks)-1 {
-				allBooksString += allBooks[i] + "\n"
-			// If not last one, just append the book with a comma
-			} else {
-				allBooksString += allBooks[i] + ", "
-			}
+	if countOnly {
+		if !f.IsTextOutput() {
+			f.EmitJSON(counts)
+			return true
 		}
-
-		f.WordWrap(allBooksString)	
-		return
+		for _, count := range counts {
+			fmt.Printf("%-16s %d\n", count.BookName, count.Count)
+		}
+		fmt.Printf("\nTotal: %d verses in %d books\n", total, len(counts))
+		return true
 	}
 
-	var passage Passage
-	passage.BookName = os.Args[1]
+	// This does the search. It uses the full text search index, so it matches whole words (or the start of words
+	// if it's not an exact search, ie love with match with loved), "quoted phrases", prefixes like bless*,
+	// AND/OR/NOT, and NEAR/5 for words close to each other
+	verses, err := b.Search(term, opts)
+	if err != nil {
+		f.PrintMessage("Error in search: %s\n", err)
+		return false
+	}
 
-	// If just a book is provided, Print number of chapters.
-	if len(os.Args) == 2 {
-		chapters := f.GetAllChaptersInBook(db, passage.BookName)
-		if chapters == 0 {
-			fmt.Printf("Can't find book \"%s\"\n\n", passage.BookName)
-			return
-		} else {
-			fmt.Printf("Chapters in %s: %d\n", passage.BookName, chapters)
-			fmt.Println()
-		}
+	if len(verses) == 0 {
+		f.PrintMessage("No more results, there are only %d\n", total)
+		return true
+	}
 
-	// if a book and a chapter, print the entire chapter
-	} else if len(os.Args) == 3 {
-		passage.Chapter = os.Args[2]
-		printChapters(db, passage)
+	// Everything goes into here first, so that if it's too long for the screen it can go through the pager
+	var output strings.Builder
+	shown := len(verses)
 
-	// if book and chapter and verse(s), print the verse(s)
-	} else if len(os.Args) == 4 {
-		passage.Chapter = os.Args[2]
-		passage.Verse = os.Args[3]
-		printVerses(db, passage)
-	} else {
-		fmt.Println("Please enter a correct verse\n")
+	for _, verse := range verses {
+		// json/csv/etc don't get highlighted or paged, they go straight out
+		if !f.IsTextOutput() {
+			f.EmitVerse(verse)
+			continue
+		}
+		fmt.Fprintf(&output, "%s %d:%d\n", verse.BookName, verse.Chapter, verse.Verse)
+		output.WriteString(f.WrapString(highlight(verse.Text)))
+		output.WriteString("\n\n")
 	}
-}
 
+	if !f.IsTextOutput() {
+		return true
+	}
 
-// This function runs if you provide only a book
-// I don't really want to use this. I don't want to print entire books...But maybe. I'll leave it for now
-func printBook() {
+	// The summary at the bottom. Total found, which ones are showing if it's only some of them, and how many in each book
+	summary := fmt.Sprintf("Found %d verses in %d books", total, len(counts))
+	if shown < total {
+		summary += fmt.Sprintf(" (showing %d-%d)", opts.Offset+1, opts.Offset+shown)
+	}
+	var perBook []string
+	for _, count := range counts {
+		perBook = append(perBook, fmt.Sprintf("%s %d", count.BookName, count.Count))
+	}
+	output.WriteString(f.WrapString(summary + ": " + strings.Join(perBook, ", ")))
+	output.WriteString("\n")
 
+	f.Page(output.String())
+	return true
 }
 
+func favoriteMode(db *sql.DB, tag string) {
+	f.ListFavorites(db, tag)
+}
 
-// This function runs if you provide 2 arguments, ie a book and a chapter or range of chapters
-func printChapters(db *sql.DB, passage Passage) {
-	// This is for a range of chapters ie "bible "1 Cornthians" 1-3"
-	if strings.Contains(passage.Chapter, "-") {
-		chapters, err := getIntsStartAndEnd(passage.Chapter)
-		if err != nil {
-			fmt.Println("Error getting all chapters: ", err)
-		}
-
-		// For every chapter
-		for i := 0 ; i < len(chapters); i++{
-			// We need to get the number of verses for the chapter
-			verses := f.GetAllVersesInChapter(db, passage.BookName, strconv.Itoa(chapters[i]))
+// This runs if no "flags" are provided, but there may be arguments. 
+// If texts has translations in it (--compare), they get printed side by side. It gives back false if something couldn't be found
+func singleShotMode(db *sql.DB, texts []f.ParallelText, args []string) bool {
+	// if no argurments provided, print all books (the same as bible -l)
+	if len(args) == 0 {
+		return listMode(db, args)
+	}
 
-			// Check if returned 0. This means the chapter doens't exist
-			if verses == 0 {
-				fmt.Printf("Can't find chapter %d in book \"%s\"\n\n", chapters[i], passage.BookName)
-				return
-			}
+	// Everything after the program name is the reference, ie "bible John 3:16", "bible 1 Cor 13:4-7" or "bible Genesis 1 1".
+	// Errors and notes go through PrintMessage, so with --format json/csv/etc they don't end up mixed in with the verses
+	ref, err := f.ParseReference(strings.Join(args, " "))
+	if err != nil {
+		f.PrintMessage("%s\n\n", err)
+		return false
+	}
 
-			fmt.Printf("%s Chapter %d\n\n", passage.BookName, chapters[i])
-			// For every verse
-			for j := 1; j <= verses; j++ {
-				f.PrintVerse(db, passage.BookName, strconv.Itoa(chapters[i]), strconv.Itoa(j))
+	ok := true
+	for _, r := range ref.Ranges {
+		// If just a book is provided, Print number of chapters.
+		if r.Chapter == 0 {
+			chapters, err := client.New(db).Chapters(r.Book)
+			if err != nil {
+				f.PrintMessage("%s\n\n", err)
+				ok = false
+				continue
 			}
-		}
+			f.PrintMessage("Chapters in %s: %d\n\n", r.Book, len(chapters))
+			continue
 
-	// This is for a single chapter ie "bible "1 Corinthians" 1"
-	} else {
-		verses := f.GetAllVersesInChapter(db, passage.BookName, passage.Chapter)
-		// Check if returned 0. This means that the chapter doesn't exist. 
-		if verses == 0 {
-			fmt.Printf("Can't find chapter %s in book \"%s\"\n\n", passage.Chapter, passage.BookName)
-			return
-		}
+		// Comparing translations, so print them side by side
+		} else if len(texts) > 0 {
+			err = f.PrintParallelRange(texts, r)
 
-		for i := 1; i <= verses; i++ {
-			f.PrintVerse(db, passage.BookName, passage.Chapter, strconv.Itoa(i))
+		// Otherwise print the chapter(s) or verse(s). This works for ranges that go over chapters or books too, ie "Malachi 4 - Matthew 1"
+		} else {
+			err = f.PrintRange(db, r)
 		}
-	}
-}
-
-
-// This function runs if you provide all 3 arguments. Ie  a book, a chapter, and a verse or range of verses.
-func printVerses(db *sql.DB, passage Passage) {
-	// This is for a range of verses ie "bible "1 Corinthians" 1 1-5"
-	if strings.Contains(passage.Verse, "-") {
-		verses, err := getIntsStartAndEnd(passage.Verse)
 		if err != nil {
-			fmt.Println("Error gettings all verses: ", err)
-		}
-		for i := 0; i < len(verses); i ++ {
-			f.PrintVerse(db, passage.BookName, passage.Chapter, strconv.Itoa(verses[i]))
+			f.PrintMessage("%s\n\n", err)
+			ok = false
+			continue
 		}
 
-	// This is for a single verse
-	} else {
-		f.PrintVerse(db, passage.BookName, passage.Chapter, passage.Verse)
+		// Remember that it was read (bible history)
+		if start, end, err := f.GetIdRange(db, r); err == nil {
+			f.LogReading(db, start, end, "reference")
+		}
 	}
+	return ok
 }
 
 
-// getIntsStartAndEnd parses a string in the format "start-end" (ie 5-10) and returns a slice of integers (ie [5,6,7,8,9,10]).
-func getIntsStartAndEnd(s string) ([]int, error) {
-	split := strings.Split(s, "-")
-	if len(split) != 2 {
-		return nil, fmt.Errorf("Invalid format, expected 'start-end'")
-	}
-
-	start, err := strconv.Atoi(split[0])
-	if err != nil {
-		return nil, fmt.Errorf("Error converting start to int: %v", err)
-	}
-
-	end, err := strconv.Atoi(split[1])
-	if err != nil {
-		return nil, fmt.Errorf("Error converting end to int: %v", err)
-	}
-
-	// Create a slice to hold the integers from start to end
-	var ints []int
-	for i := start; i <= end; i++ {
-		ints = append(ints, i)
-	}
+// This function runs if you provide only a book
+// I don't really want to use this. I don't want to print entire books...But maybe. I'll leave it for now
+func printBook() {
 
-	return ints, nil
 }
 
 
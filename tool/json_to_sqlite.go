@@ -28,8 +28,19 @@ type BibleData struct {
 func main() {
     fmt.Println("Starting the JSON to SQLite conversion...")
 
+    // The json file and the database can be given as arguments (ie "go run json_to_sqlite.go web.json web.db")
+    // to make other translations. Otherwise it makes the KJV like it always has.
+    jsonPath := "kjv.json"
+    dbPath := "./kjv.db"
+    if len(os.Args) > 1 {
+        jsonPath = os.Args[1]
+    }
+    if len(os.Args) > 2 {
+        dbPath = os.Args[2]
+    }
+
     // Read the JSON file
-    jsonFile, err := os.Open("kjv.json")
+    jsonFile, err := os.Open(jsonPath)
     if err != nil {
         log.Fatalf("Error opening JSON file: %v\n", err)
     }
@@ -51,7 +62,7 @@ func main() {
     fmt.Printf("Successfully unmarshaled JSON data. Found %d verses.\n", len(bibleData.Verses))
 
     // Create or open the SQLite database
-    db, err := sql.Open("sqlite3", "./kjv.db")
+    db, err := sql.Open("sqlite3", dbPath)
     if err != nil {
         log.Fatalf("Error opening SQLite database: %v\n", err)
     }
@@ -82,5 +93,17 @@ func main() {
     }
 
     fmt.Println("All verses successfully inserted into the SQLite database.")
-}
 
+    // Build the full text search index so searching doesn't have to do it the first time.
+    // This needs fts5, so build this with "-tags sqlite_fts5" (ie "go run -tags sqlite_fts5 json_to_sqlite.go").
+    // Without it the bible table is still fine, search just falls back to LIKE like it does in the program
+    createIndexSQL := `CREATE VIRTUAL TABLE IF NOT EXISTS bible_fts USING fts5(text, content='bible', content_rowid='id');`
+    if _, err := db.Exec(createIndexSQL); err != nil {
+        fmt.Printf("Warning: skipping the search index, this needs to be built with -tags sqlite_fts5 (%v)\n", err)
+        return
+    }
+    if _, err := db.Exec("INSERT INTO bible_fts(bible_fts) VALUES('rebuild');"); err != nil {
+        log.Fatalf("Error building search index: %v\n", err)
+    }
+    fmt.Println("Successfully built the search index.")
+}